@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: allowances <command>
+
+commands:
+  list                               list recorded approvals on Base with what is left of them
+  approve <wallet> <token>           approve the router sells go through to sell a token ahead of time
+  revoke <wallet> <token> [spender]  revoke an approval, of the router sells go through by default
+  revoke-all                         revoke every recorded approval still in use`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+
+	if os.Args[1] == "list" {
+		client, err := cmd.DialBase(ctx, configurations)
+		if err != nil {
+			log.Fatalf("Failed to connect to Base RPC: %v", err)
+		}
+		approvals, err := db.ListApprovals(ctx, "base")
+		if err != nil {
+			log.Fatalf("Failed to list approvals: %v", err)
+		}
+		for _, approval := range approvals {
+			allowance, err := evm.GetAllowance(ctx, client, common.HexToAddress(approval.Token), common.HexToAddress(approval.Owner), common.HexToAddress(approval.Spender))
+			left := "unknown"
+			if err != nil {
+				log.Printf("Failed to read allowance of %s for %s: %v", approval.Token, approval.Spender, err)
+			} else if allowance.Cmp(evm.MaxAllowance) == 0 {
+				left = "unlimited"
+			} else {
+				left = allowance.String()
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\n", approval.Owner, approval.Token, approval.Spender, left, approval.TxHash)
+		}
+		return
+	}
+
+	signers, err := cmd.LoadSigners(configurations, db)
+	if err != nil {
+		log.Fatalf("Failed to load signers: %v", err)
+	}
+	router, err := cmd.NewBaseRouter(ctx, configurations, signers)
+	if err != nil {
+		log.Fatalf("Failed to set up router: %v", err)
+	}
+	chain, err := router.Chain("base")
+	if err != nil {
+		log.Fatalf("Failed to set up router: %v", err)
+	}
+	executor := cmd.NewLiveExecutor(chain, router.Clients["base"], router, db)
+	allowances := executor.Allowances
+	// Permit2 when sells go through the Universal Router, the Uniswap router otherwise
+	sellSpender, err := executor.SellSpender()
+	if err != nil {
+		log.Fatalf("Failed to resolve the sell spender: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "approve":
+		if len(os.Args) < 4 || !common.IsHexAddress(os.Args[2]) || !common.IsHexAddress(os.Args[3]) {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		if err := executor.ApproveSells(ctx, common.HexToAddress(os.Args[2]), common.HexToAddress(os.Args[3])); err != nil {
+			log.Fatalf("Failed to approve: %v", err)
+		}
+	case "revoke":
+		if len(os.Args) < 4 || !common.IsHexAddress(os.Args[2]) || !common.IsHexAddress(os.Args[3]) {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		spender := sellSpender
+		if len(os.Args) > 4 {
+			if !common.IsHexAddress(os.Args[4]) {
+				fmt.Println(usage)
+				os.Exit(2)
+			}
+			spender = common.HexToAddress(os.Args[4])
+		}
+		hash, err := allowances.Revoke(ctx, "base", common.HexToAddress(os.Args[2]), common.HexToAddress(os.Args[3]), spender)
+		if err != nil {
+			log.Fatalf("Failed to revoke: %v", err)
+		}
+		fmt.Println(hash)
+	case "revoke-all":
+		hashes, err := allowances.RevokeAll(ctx, "base")
+		for _, hash := range hashes {
+			fmt.Println(hash)
+		}
+		if err != nil {
+			log.Fatalf("Failed to revoke approvals: %v", err)
+		}
+		log.Printf("Revoked %d approvals", len(hashes))
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
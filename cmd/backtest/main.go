@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	"copytrader/internal/backtest"
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: backtest <leader> <from-block> <to-block> [latency-blocks] [strategy]
+
+Replays a leader's Base swaps between two blocks, copying each one latency-blocks later
+(default 1) with the named strategy (default strategy if omitted). Needs an archive node.`
+
+func main() {
+	if len(os.Args) < 4 || !common.IsHexAddress(os.Args[1]) {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	leader := common.HexToAddress(os.Args[1])
+	fromBlock, err := strconv.ParseUint(os.Args[2], 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid from block: %v", err)
+	}
+	toBlock, err := strconv.ParseUint(os.Args[3], 10, 64)
+	if err != nil || toBlock < fromBlock {
+		log.Fatalf("Invalid to block: %s", os.Args[3])
+	}
+	latency := uint64(1)
+	if len(os.Args) > 4 {
+		latency, err = strconv.ParseUint(os.Args[4], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid latency: %v", err)
+		}
+	}
+
+	configurations := cmd.LoadConfig()
+	strategy := engine.DefaultStrategy
+	var db *database.Database
+	if len(os.Args) > 5 {
+		db, err = database.NewDatabase(configurations.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		strategy, err = db.GetStrategyByName(context.Background(), os.Args[5])
+		if err != nil {
+			log.Fatalf("Failed to load strategy %s: %v", os.Args[5], err)
+		}
+	}
+
+	chain, err := cmd.BaseChain(configurations)
+	if err != nil {
+		log.Fatalf("Failed to configure Base: %v", err)
+	}
+	client, err := cmd.DialBase(context.Background(), configurations)
+	if err != nil {
+		log.Fatalf("Failed to connect to Base RPC: %v", err)
+	}
+
+	tokens := evm.NewTokenRegistry("base", client, nil, db, configurations.TokenCacheTTL)
+
+	backtester := &backtest.Backtester{
+		Chain:    "base",
+		Client:   client,
+		Factory:  chain.UniswapV2Factory(),
+		WETH:     chain.WETH,
+		Strategy: strategy,
+		Latency:  latency,
+		BuyGas:   uint64(configurations.PaperBuyGas),
+		SellGas:  uint64(configurations.PaperSellGas),
+	}
+	trades, summary, err := backtester.Run(context.Background(), leader, fromBlock, toBlock)
+	if err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+
+	fmt.Println("block\tcopy block\tside\ttoken\teth\ttokens\tpnl\tleader tx")
+	for _, trade := range trades {
+		signal := trade.Signal
+		if trade.Skipped != "" {
+			fmt.Printf("%d\t%d\t%s\t%s\tskipped: %s\t\t\t%s\n", signal.BlockNumber, trade.Block, signal.Side, signal.Token.Hex(), trade.Skipped, signal.TxHash.Hex())
+			continue
+		}
+		pnl := ""
+		if trade.PnL != nil {
+			pnl = evm.FormatUnits(trade.PnL, 18)
+		}
+		leaderTx := signal.TxHash.Hex()
+		if trade.Exit != "" {
+			leaderTx = trade.Exit
+		}
+		fmt.Printf("%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", signal.BlockNumber, trade.Block, signal.Side, signal.Token.Hex(),
+			evm.FormatUnits(trade.ETHAmount, 18), tokens.FormatAmount(context.Background(), signal.Token, trade.TokenAmount), pnl, leaderTx)
+	}
+
+	fmt.Printf("\nstrategy %s, latency %d blocks, blocks %d-%d\n", strategy.Name, latency, fromBlock, toBlock)
+	fmt.Printf("signals %d, buys %d, sells %d, skipped %d\n", summary.Signals, summary.Buys, summary.Sells, summary.Skipped)
+	fmt.Printf("closed %d, win rate %.1f%%\n", summary.Wins+summary.Losses, summary.WinRate())
+	fmt.Printf("realised %s ETH, max drawdown %s ETH\n", evm.FormatUnits(summary.Realised, 18), evm.FormatUnits(summary.MaxDrawdown, 18))
+	fmt.Printf("open cost %s ETH, open value %s ETH\n", evm.FormatUnits(summary.OpenCost, 18), evm.FormatUnits(summary.OpenValue, 18))
+	fmt.Printf("total %s ETH\n", evm.FormatUnits(summary.Total(), 18))
+}
@@ -1,22 +1,67 @@
 package cmd
 
 import (
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	PrivateKey         string
 	PublicKey          string
 	ChainID            string
+	ChainsFile         string
 	BaseRPC            string
+	BaseWS             string
+	RPCTimeout         time.Duration
 	UniswapBaseRouter  string
+	UniversalRouter    string
 	UniswapBaseFactory string
 	WethBaseAddress    string
 	Redis              string
 	DatabaseURL        string
+	MinLiquidityETH    float64
+	MinLiquidityUSD    float64
+	MaxPriceImpact     float64
+	DownsizeOnImpact   bool
+	TokenCacheTTL      time.Duration
+	PriceSources       string
+	EthUsdFeed         string
+	UsdcBaseAddress    string
+	PriceMaxAge        time.Duration
+	KeystoreDir        string
+	PassphraseFile     string
+	RemoteSignerURL    string
+	RemoteSignerMethod string
+	RemoteSigners      string
+	MnemonicFile       string
+	AllowlistOnly      bool
+	MinTokenAge        time.Duration
+	MinHolders         int
+	HoldersAPIURL      string
+	MaxRiskScore       int
+	MaxOpenPositions   int
+	MaxTokenExposure   float64
+	MaxTotalExposure   float64
+	MaxTradesPerHour   int
+	DailyLossLimit     float64
+	LeaderCooldown     time.Duration
+	TokenCooldown      time.Duration
+	CooldownMode       string
+	MaxSignalAge       time.Duration
+	PaperTrading       bool
+	PaperSlippagePct   float64
+	PaperBuyGas        int
+	PaperSellGas       int
+	PaperGasPriceGwei  float64
+	PrivateRPCURL      string
+	BundleRPCURL       string
+	BundleBlocks       int
+	BundleAuthKeyFile  string
 }
 
 func LoadConfig() *Config {
@@ -28,14 +73,115 @@ func LoadConfig() *Config {
 
 	// Return the configuration struct populated with environment variables
 	return &Config{
-		PrivateKey:         os.Getenv("PRIVATE_KEY"),
 		PublicKey:          os.Getenv("PUBLIC_KEY"),
 		ChainID:            os.Getenv("CHAIN_ID"),
+		ChainsFile:         os.Getenv("CHAINS_FILE"),
 		Redis:              os.Getenv("REDIS"),
 		BaseRPC:            os.Getenv("BASE_RPC"),
+		BaseWS:             os.Getenv("BASE_WS"),
+		RPCTimeout:         getEnvDuration("RPC_TIMEOUT", evm.DefaultCallTimeout),
 		UniswapBaseRouter:  os.Getenv("UNISWAP_BASE_ROUTER"),
+		UniversalRouter:    os.Getenv("UNIVERSAL_BASE_ROUTER"),
 		DatabaseURL:        os.Getenv("DATABASE_URL"),
 		UniswapBaseFactory: os.Getenv("UNISWAP_BASE_FACTORY"),
 		WethBaseAddress:    os.Getenv("WETH_BASE_ADDRESS"),
+		MinLiquidityETH:    getEnvFloat("MIN_LIQUIDITY_ETH", 0),
+		MinLiquidityUSD:    getEnvFloat("MIN_LIQUIDITY_USD", 0),
+		MaxPriceImpact:     getEnvFloat("MAX_PRICE_IMPACT", 0),
+		DownsizeOnImpact:   getEnvBool("DOWNSIZE_ON_IMPACT", false),
+		TokenCacheTTL:      getEnvDuration("TOKEN_CACHE_TTL", 24*time.Hour),
+		PriceSources:       getEnv("PRICE_SOURCES", "chainlink,pool,coingecko"),
+		EthUsdFeed:         getEnv("ETH_USD_FEED", "0x71041dddad3595F9CEd3DcCFBe3D1F4b0a16Bb70"),
+		UsdcBaseAddress:    getEnv("USDC_BASE_ADDRESS", "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"),
+		PriceMaxAge:        getEnvDuration("PRICE_MAX_AGE", time.Hour),
+		KeystoreDir:        getEnv("KEYSTORE_DIR", "keystore"),
+		PassphraseFile:     os.Getenv("KEYSTORE_PASSPHRASE_FILE"),
+		RemoteSignerURL:    os.Getenv("REMOTE_SIGNER_URL"),
+		RemoteSignerMethod: getEnv("REMOTE_SIGNER_METHOD", "eth_signTransaction"),
+		RemoteSigners:      os.Getenv("REMOTE_SIGNER_ACCOUNTS"),
+		MnemonicFile:       os.Getenv("MNEMONIC_FILE"),
+		AllowlistOnly:      getEnvBool("FILTER_ALLOWLIST_ONLY", false),
+		MinTokenAge:        getEnvDuration("FILTER_MIN_TOKEN_AGE", 0),
+		MinHolders:         getEnvInt("FILTER_MIN_HOLDERS", 0),
+		HoldersAPIURL:      getEnv("HOLDERS_API_URL", "https://base.blockscout.com"),
+		MaxRiskScore:       getEnvInt("MAX_RISK_SCORE", 50),
+		MaxOpenPositions:   getEnvInt("MAX_OPEN_POSITIONS", 0),
+		MaxTokenExposure:   getEnvFloat("MAX_TOKEN_EXPOSURE_ETH", 0),
+		MaxTotalExposure:   getEnvFloat("MAX_TOTAL_EXPOSURE_ETH", 0),
+		MaxTradesPerHour:   getEnvInt("MAX_TRADES_PER_HOUR", 0),
+		DailyLossLimit:     getEnvFloat("DAILY_LOSS_LIMIT_ETH", 0),
+		LeaderCooldown:     getEnvDuration("COOLDOWN_LEADER_WINDOW", time.Minute),
+		TokenCooldown:      getEnvDuration("COOLDOWN_TOKEN_WINDOW", 0),
+		CooldownMode:       getEnv("COOLDOWN_MODE", "skip"),
+		MaxSignalAge:       getEnvDuration("MAX_SIGNAL_AGE", engine.DefaultMaxSignalAge),
+		PaperTrading:       getEnvBool("PAPER_TRADING", false),
+		PaperSlippagePct:   getEnvFloat("PAPER_SLIPPAGE_PCT", 0.5),
+		PaperBuyGas:        getEnvInt("PAPER_BUY_GAS", 150000),
+		PaperSellGas:       getEnvInt("PAPER_SELL_GAS", 200000),
+		PaperGasPriceGwei:  getEnvFloat("PAPER_GAS_PRICE_GWEI", 0),
+		PrivateRPCURL:      os.Getenv("PRIVATE_RPC_URL"),
+		BundleRPCURL:       os.Getenv("BUNDLE_RPC_URL"),
+		BundleBlocks:       getEnvInt("BUNDLE_BLOCKS", evm.DefaultBundleBlocks),
+		BundleAuthKeyFile:  os.Getenv("BUNDLE_AUTH_KEY_FILE"),
 	}
 }
+
+// getEnv reads a string environment variable, falling back to def when unset.
+func getEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getEnvFloat reads a float environment variable, falling back to def when unset.
+func getEnvFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return parsed
+}
+
+// getEnvInt reads an integer environment variable, falling back to def when unset.
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return parsed
+}
+
+// getEnvBool reads a boolean environment variable, falling back to def when unset.
+func getEnvBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return parsed
+}
+
+// getEnvDuration reads a duration environment variable such as "30s", falling back to def when unset.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", key, err)
+	}
+	return parsed
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/discovery"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: discover <command>
+
+commands:
+  scan <from-block> <to-block> <pair>...  score the wallets trading Base token/WETH pairs
+  top [n]                                 show the n best scored wallets (default 20)
+  promote <address> [label]               start copying a scored wallet`
+
+const chain = "base"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "scan":
+		if len(os.Args) < 5 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		fromBlock, err := strconv.ParseUint(os.Args[2], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid from block: %v", err)
+		}
+		toBlock, err := strconv.ParseUint(os.Args[3], 10, 64)
+		if err != nil || toBlock < fromBlock {
+			log.Fatalf("Invalid to block: %s", os.Args[3])
+		}
+		var pairs []common.Address
+		for _, arg := range os.Args[4:] {
+			if !common.IsHexAddress(arg) {
+				log.Fatalf("Invalid pair address: %s", arg)
+			}
+			pairs = append(pairs, common.HexToAddress(arg))
+		}
+
+		baseChain, err := cmd.BaseChain(configurations)
+		if err != nil {
+			log.Fatalf("Failed to configure Base: %v", err)
+		}
+		client, err := cmd.DialBase(ctx, configurations)
+		if err != nil {
+			log.Fatalf("Failed to connect to Base RPC: %v", err)
+		}
+		scanner := &discovery.Scanner{
+			Chain:  chain,
+			Client: client,
+			WETH:   baseChain.WETH,
+		}
+		scores, err := scanner.Scan(ctx, pairs, fromBlock, toBlock)
+		if err != nil {
+			log.Fatalf("Scan failed: %v", err)
+		}
+		if err := db.SaveLeaderScores(ctx, scores); err != nil {
+			log.Fatalf("Failed to save scores: %v", err)
+		}
+		fmt.Printf("scored %d wallets over blocks %d-%d\n", len(scores), fromBlock, toBlock)
+		printScores(scores[:min(len(scores), 20)])
+	case "top":
+		limit := 20
+		if len(os.Args) > 2 {
+			limit, err = strconv.Atoi(os.Args[2])
+			if err != nil || limit <= 0 {
+				log.Fatalf("Invalid count: %s", os.Args[2])
+			}
+		}
+		scores, err := db.ListLeaderScores(ctx, chain, limit)
+		if err != nil {
+			log.Fatalf("Failed to list scores: %v", err)
+		}
+		printScores(scores)
+	case "promote":
+		if len(os.Args) < 3 || !common.IsHexAddress(os.Args[2]) {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		address := common.HexToAddress(os.Args[2]).Hex()
+		score, err := db.GetLeaderScore(ctx, chain, address)
+		if err != nil {
+			log.Fatalf("Failed to load score for %s: %v", address, err)
+		}
+		leader := &database.Leader{
+			Address: address,
+			Chain:   chain,
+			Label:   strings.Join(os.Args[3:], " "),
+			Enabled: true,
+			Notes: fmt.Sprintf("discovered over blocks %d-%d: score %.4f, realised %.4f ETH, win rate %.1f%%, %d sells",
+				score.FromBlock, score.ToBlock, score.Score, score.RealisedETH, score.WinRate, score.Sells),
+		}
+		if err := db.CreateLeader(ctx, leader); err != nil {
+			log.Fatalf("Failed to promote leader %s: %v", address, err)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+func printScores(scores []database.LeaderScore) {
+	fmt.Println("address\tscore\trealised eth\twin rate\tbuys\tsells\tavg hold blocks\tblocks")
+	for _, score := range scores {
+		fmt.Printf("%s\t%.4f\t%.4f\t%.1f%%\t%d\t%d\t%.1f\t%d-%d\n", score.Address, score.Score, score.RealisedETH,
+			score.WinRate, score.Buys, score.Sells, score.AvgHoldBlocks, score.FromBlock, score.ToBlock)
+	}
+}
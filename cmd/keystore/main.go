@@ -0,0 +1,61 @@
+package main
+
+import (
+	"copytrader/cmd"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+const usage = `usage: keystore <command>
+
+commands:
+  new      generate a new encrypted follower wallet
+  import   encrypt an existing hex private key read from stdin
+  list     list the wallets in the keystore`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	keys := evm.OpenKeyStore(configurations.KeystoreDir)
+
+	switch os.Args[1] {
+	case "new":
+		passphrase, err := cmd.ReadPassphrase(configurations, "Passphrase: ")
+		if err != nil {
+			log.Fatalf("Failed to read passphrase: %v", err)
+		}
+		address, err := keys.NewAccount(passphrase)
+		if err != nil {
+			log.Fatalf("Failed to create wallet: %v", err)
+		}
+		fmt.Println(address.Hex())
+	case "import":
+		privateKey, err := cmd.ReadSecret("Private key: ")
+		if err != nil {
+			log.Fatalf("Failed to read private key: %v", err)
+		}
+		passphrase, err := cmd.ReadPassphrase(configurations, "Passphrase: ")
+		if err != nil {
+			log.Fatalf("Failed to read passphrase: %v", err)
+		}
+		address, err := keys.ImportHexKey(strings.TrimSpace(privateKey), passphrase)
+		if err != nil {
+			log.Fatalf("Failed to import wallet: %v", err)
+		}
+		fmt.Println(address.Hex())
+	case "list":
+		for _, address := range keys.Accounts() {
+			fmt.Println(address.Hex())
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const usage = `usage: killswitch <command>
+
+commands:
+  on [--liquidate]  block new buys, optionally selling every open position
+  off               resume trading
+  status            show the kill switch, exposure and today's realised PnL`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	risk := cmd.NewRiskManager(configurations, db)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "on":
+		liquidate := len(os.Args) > 2 && os.Args[2] == "--liquidate"
+		if err := risk.SetKillSwitch(ctx, true, liquidate); err != nil {
+			log.Fatalf("Failed to turn kill switch on: %v", err)
+		}
+		if liquidate {
+			fmt.Println("Kill switch on, open positions will be sold by the running server")
+		} else {
+			fmt.Println("Kill switch on")
+		}
+	case "off":
+		if err := risk.SetKillSwitch(ctx, false, false); err != nil {
+			log.Fatalf("Failed to turn kill switch off: %v", err)
+		}
+		fmt.Println("Kill switch off")
+	case "status":
+		halted, err := risk.Halted(ctx)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		liquidating, err := risk.LiquidationRequested(ctx)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		exposure, err := risk.Exposure(ctx)
+		if err != nil {
+			log.Fatalf("Failed to compute exposure: %v", err)
+		}
+		trades, err := risk.TradesSince(ctx, time.Now().Add(-time.Hour))
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		pnl, err := risk.RealisedPnL(ctx, time.Now().UTC().Truncate(24*time.Hour))
+		if err != nil {
+			log.Fatalf("Failed to compute realised PnL: %v", err)
+		}
+
+		fmt.Printf("kill switch\t%v\tliquidation pending=%v\n", halted, liquidating)
+		fmt.Printf("open positions\t%d\tlimit %d\n", exposure.OpenPositions, risk.Limits.MaxOpenPositions)
+		fmt.Printf("total exposure\t%s ETH\tlimit %g\n", evm.FormatUnits(exposure.Total, 18), risk.Limits.MaxTotalExposureETH)
+		for token, cost := range exposure.Tokens {
+			fmt.Printf("  %s\t%s ETH\tlimit %g\n", token.Hex(), evm.FormatUnits(cost, 18), risk.Limits.MaxTokenExposureETH)
+		}
+		fmt.Printf("trades last hour\t%d\tlimit %d\n", trades, risk.Limits.MaxTradesPerHour)
+		fmt.Printf("realised PnL today\t%s ETH\tloss limit %g\n", evm.FormatUnits(pnl, 18), risk.Limits.DailyLossLimitETH)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const usage = `usage: latency [--paper] [days]
+
+Reports copy latency and price difference percentiles per leader and for Base as a whole, over
+the buys of the last days (default all), for live trades or with --paper for paper trades.
+PAPER_TRADING=true also selects the paper trades.`
+
+func main() {
+	configurations := cmd.LoadConfig()
+	var since time.Time
+	for _, arg := range os.Args[1:] {
+		if arg == "--paper" {
+			configurations.PaperTrading = true
+			continue
+		}
+		days, err := strconv.Atoi(arg)
+		if err != nil || days <= 0 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		since = time.Now().AddDate(0, 0, -days)
+	}
+
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	report, err := engine.LatencyReport(context.Background(), cmd.TradeDatabase(configurations, db), "base", since)
+	if err != nil {
+		log.Fatalf("Failed to build latency report: %v", err)
+	}
+
+	fmt.Println("leader\tmetric\tcount\tp50\tp90\tp99\tmax")
+	for _, result := range report {
+		leader := result.Leader
+		if leader == "" {
+			leader = "all"
+		}
+		for _, metric := range []struct {
+			name   string
+			values engine.Percentiles
+		}{
+			{"detect s", result.Detect},
+			{"broadcast s", result.Broadcast},
+			{"inclusion s", result.Inclusion},
+			{"blocks behind", result.BlocksBehind},
+			{"price diff %", result.PriceDiffPct},
+		} {
+			v := metric.values
+			fmt.Printf("%s\t%s\t%d\t%.3f\t%.3f\t%.3f\t%.3f\n", leader, metric.name, v.Count, v.P50, v.P90, v.P99, v.Max)
+		}
+	}
+}
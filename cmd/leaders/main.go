@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: leaders <command>
+
+commands:
+  add <address> [label]      start copying a leader on Base
+  list                       list tracked leaders
+  enable <address>           resume copying a leader
+  disable <address>          pause copying a leader
+  note <address> <text>      attach notes to a leader
+  wallet <address> <wallet>  copy a leader from its own follower wallet
+  remove <address>           stop tracking a leader`
+
+const chain = "base"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+
+	if os.Args[1] == "list" {
+		leaders, err := db.ListLeaders(ctx, chain)
+		if err != nil {
+			log.Fatalf("Failed to list leaders: %v", err)
+		}
+		for _, leader := range leaders {
+			fmt.Printf("%s\t%s\tenabled=%v\tadded=%s\t%s\n", leader.Address, leader.Label, leader.Enabled, leader.AddedAt.Format("2006-01-02"), leader.Notes)
+		}
+		return
+	}
+
+	if len(os.Args) < 3 || !common.IsHexAddress(os.Args[2]) {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	address := common.HexToAddress(os.Args[2]).Hex()
+
+	switch os.Args[1] {
+	case "add":
+		leader := &database.Leader{
+			Address: address,
+			Chain:   chain,
+			Label:   strings.Join(os.Args[3:], " "),
+			Enabled: true,
+		}
+		err = db.CreateLeader(ctx, leader)
+	case "enable":
+		err = db.SetLeaderEnabled(ctx, chain, address, true)
+	case "disable":
+		err = db.SetLeaderEnabled(ctx, chain, address, false)
+	case "note":
+		var leader database.Leader
+		leader, err = db.GetLeader(ctx, chain, address)
+		if err == nil {
+			leader.Notes = strings.Join(os.Args[3:], " ")
+			err = db.UpdateLeader(ctx, &leader)
+		}
+	case "wallet":
+		if len(os.Args) < 4 || !common.IsHexAddress(os.Args[3]) {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		var leader database.Leader
+		leader, err = db.GetLeader(ctx, chain, address)
+		if err == nil {
+			leader.Wallet = common.HexToAddress(os.Args[3]).Hex()
+			err = db.UpdateLeader(ctx, &leader)
+		}
+	case "remove":
+		err = db.DeleteLeader(ctx, chain, address)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Failed to %s leader %s: %v", os.Args[1], address, err)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// TradeDatabase returns the view of db that trades are recorded in, paper or live.
+func TradeDatabase(config *Config, db *database.Database) *database.Database {
+	if config.PaperTrading {
+		return db.PaperTrades()
+	}
+	return db
+}
+
+// NewPaperExecutor builds the swap simulator used in paper trading mode, quoting through the
+// chain's Uniswap V2 router.
+func NewPaperExecutor(config *Config, chain *evm.ChainConfig, client *ethclient.Client, db *database.Database) *engine.PaperExecutor {
+	executor := &engine.PaperExecutor{
+		Client:        client,
+		DB:            db.PaperTrades(),
+		UniswapRouter: chain.UniswapV2Router(),
+		WETH:          chain.WETH,
+		SlippagePct:   config.PaperSlippagePct,
+		BuyGas:        uint64(config.PaperBuyGas),
+		SellGas:       uint64(config.PaperSellGas),
+	}
+	if config.PaperGasPriceGwei > 0 {
+		gasPrice, err := evm.ParseUnits(strconv.FormatFloat(config.PaperGasPriceGwei, 'f', -1, 64), 9)
+		if err == nil {
+			executor.GasPrice = gasPrice
+		}
+	}
+	return executor
+}
+
+// NewLiveExecutor sends the engine's swaps on chain through router, recording approvals in db.
+// Sells go through the Universal Router when the chain configures one.
+func NewLiveExecutor(chain *evm.ChainConfig, client *ethclient.Client, router *evm.MultiChainRouter, db *database.Database) *engine.LiveExecutor {
+	return &engine.LiveExecutor{
+		Chain:           chain.Name,
+		Client:          client,
+		Router:          router,
+		UniswapRouter:   chain.UniswapV2Router(),
+		UniversalRouter: chain.Routers[evm.RouterUniversal],
+		WETH:            chain.WETH,
+		Allowances:      evm.NewAllowanceManager(router, db),
+	}
+}
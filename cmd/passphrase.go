@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ReadPassphrase returns the keystore passphrase from the configured file, or prompts for it on stdin.
+func ReadPassphrase(config *Config, prompt string) (string, error) {
+	if config.PassphraseFile != "" {
+		raw, err := os.ReadFile(config.PassphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %v", err)
+		}
+		return strings.TrimRight(string(raw), "\r\n"), nil
+	}
+
+	line, err := ReadSecret(prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// ReadMnemonic returns the HD wallet mnemonic from the configured file, or prompts for it on stdin.
+func ReadMnemonic(config *Config) (string, error) {
+	if config.MnemonicFile != "" {
+		raw, err := os.ReadFile(config.MnemonicFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read mnemonic file: %v", err)
+		}
+		return strings.TrimSpace(string(raw)), nil
+	}
+
+	line, err := ReadSecret("Mnemonic: ")
+	if err != nil {
+		return "", fmt.Errorf("failed to read mnemonic: %v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// stdin is shared by every ReadSecret call, so input buffered past one line when it is piped
+// is still there for the next prompt.
+var stdin = bufio.NewReader(os.Stdin)
+
+// ReadSecret prompts for a line on stdin without echoing it when stdin is a terminal. A last
+// line without a trailing newline is accepted.
+func ReadSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		secret, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(secret), err
+	}
+	line, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) && line != "" {
+		return line, nil
+	}
+	return line, err
+}
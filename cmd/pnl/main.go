@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+)
+
+const usage = `usage: pnl [--paper]
+
+Reports realised and unrealised PnL per leader, for live trades or with --paper for paper trades.
+PAPER_TRADING=true also selects the paper trades.`
+
+func main() {
+	configurations := cmd.LoadConfig()
+	for _, arg := range os.Args[1:] {
+		switch arg {
+		case "--paper":
+			configurations.PaperTrading = true
+		default:
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+	}
+
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	chain, err := cmd.BaseChain(configurations)
+	if err != nil {
+		log.Fatalf("Failed to configure Base: %v", err)
+	}
+	client, err := cmd.DialBase(context.Background(), configurations)
+	if err != nil {
+		log.Fatalf("Failed to connect to Base RPC: %v", err)
+	}
+
+	// Balances are all the report needs from the executor, so no signers are loaded
+	var executor engine.Executor = cmd.NewLiveExecutor(chain, client, nil, db)
+	if configurations.PaperTrading {
+		executor = cmd.NewPaperExecutor(configurations, chain, client, db)
+	}
+	reporter := &engine.Engine{
+		Chain:         "base",
+		Client:        client,
+		DB:            cmd.TradeDatabase(configurations, db),
+		Executor:      executor,
+		UniswapRouter: chain.UniswapV2Router(),
+		WETH:          chain.WETH,
+	}
+
+	report, err := reporter.PnLReport(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to build PnL report: %v", err)
+	}
+
+	fmt.Printf("%s trades\n", executor.Name())
+	fmt.Println("leader\tbuys\tsells\trealised\topen cost\topen value\tunrealised")
+	realised, unrealised := new(big.Int), new(big.Int)
+	for _, result := range report {
+		fmt.Printf("%s\t%d\t%d\t%s\t%s\t%s\t%s\n", result.Leader, result.Buys, result.Sells,
+			evm.FormatUnits(result.Realised, 18), evm.FormatUnits(result.OpenCost, 18),
+			evm.FormatUnits(result.OpenValue, 18), evm.FormatUnits(result.Unrealised(), 18))
+		realised.Add(realised, result.Realised)
+		unrealised.Add(unrealised, result.Unrealised())
+	}
+	fmt.Printf("total realised %s ETH, unrealised %s ETH\n", evm.FormatUnits(realised, 18), evm.FormatUnits(unrealised, 18))
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+)
+
+// NewRiskManager builds the Base risk manager from the configured limits. In paper trading
+// mode the limits apply to the paper trades.
+func NewRiskManager(config *Config, db *database.Database) *engine.RiskManager {
+	return &engine.RiskManager{
+		Chain: "base",
+		DB:    TradeDatabase(config, db),
+		Limits: engine.RiskLimits{
+			MaxOpenPositions:    config.MaxOpenPositions,
+			MaxTokenExposureETH: config.MaxTokenExposure,
+			MaxTotalExposureETH: config.MaxTotalExposure,
+			MaxTradesPerHour:    config.MaxTradesPerHour,
+			DailyLossLimitETH:   config.DailyLossLimit,
+		},
+	}
+}
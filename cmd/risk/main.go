@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: risk <token>
+
+Reports the token owner, the admin functions found in its bytecode and its risk score.`
+
+func main() {
+	if len(os.Args) < 2 || !common.IsHexAddress(os.Args[1]) {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	client, err := cmd.DialBase(context.Background(), configurations)
+	if err != nil {
+		log.Fatalf("Failed to connect to Base RPC: %v", err)
+	}
+
+	report, err := evm.AnalyseTokenRisk(context.Background(), client, common.HexToAddress(os.Args[1]))
+	if err != nil {
+		log.Fatalf("Failed to analyse token: %v", err)
+	}
+
+	fmt.Printf("token\t%s\n", report.Token.Hex())
+	if report.HasOwner {
+		fmt.Printf("owner\t%s\trenounced=%v\n", report.Owner.Hex(), report.Renounced)
+	} else {
+		fmt.Println("owner\tnone")
+	}
+	for _, capability := range report.Capabilities {
+		fmt.Printf("can\t%s\t+%d\n", capability, evm.CapabilityWeights[capability])
+	}
+	fmt.Printf("score\t%d\trejected above %d\n", report.Score, configurations.MaxRiskScore)
+}
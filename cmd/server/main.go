@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
 	"copytrader/cmd"
+	"copytrader/internal/cache"
 	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 func main() {
@@ -15,4 +27,175 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	// Step 4: Migrate the database schema
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Step 5: Connect to Redis and Base, and set up the token registry
+	redisCache, err := cache.NewCache(configurations.Redis)
+	if err != nil {
+		log.Fatalf("Failed to connect to redis: %v", err)
+	}
+	// Live trading reads the chain through the router's client, so Base is only dialled once
+	var router *evm.MultiChainRouter
+	var baseClient *ethclient.Client
+	baseChain, err := cmd.BaseChain(configurations)
+	if err != nil {
+		log.Fatalf("Failed to configure Base: %v", err)
+	}
+	if configurations.PaperTrading {
+		baseClient, err = cmd.DialBase(ctx, configurations)
+		if err != nil {
+			log.Fatalf("Failed to connect to Base RPC: %v", err)
+		}
+	} else {
+		signers, err := cmd.LoadSigners(configurations, db)
+		if err != nil {
+			log.Fatalf("Failed to load signers: %v", err)
+		}
+		log.Printf("Loaded %d transaction signers", len(signers))
+		router, err = cmd.NewBaseRouter(ctx, configurations, signers)
+		if err != nil {
+			log.Fatalf("Failed to set up router: %v", err)
+		}
+		baseChain, _ = router.Chain("base")
+		baseClient = router.Clients["base"]
+	}
+	tokens := evm.NewTokenRegistry("base", baseClient, redisCache, db, configurations.TokenCacheTTL)
+	log.Printf("Token registry ready for chain %s (TTL %s)", tokens.Chain, tokens.TTL)
+	// Step 6: Set up the ETH/USD price sources
+	prices := buildPriceSource(configurations, baseChain, baseClient)
+	log.Printf("ETH/USD price sources: %s", prices.Name())
+	// Step 7: Set up the pre-trade liquidity guard
+	guard := &evm.LiquidityGuard{
+		MinLiquidityETH: configurations.MinLiquidityETH,
+		MinLiquidityUSD: configurations.MinLiquidityUSD,
+		MaxPriceImpact:  configurations.MaxPriceImpact,
+		Downsize:        configurations.DownsizeOnImpact,
+		Prices:          prices,
+	}
+	log.Printf("Liquidity guard: min %.4f ETH / $%.2f, max impact %.2f%%, downsize %v",
+		guard.MinLiquidityETH, guard.MinLiquidityUSD, guard.MaxPriceImpact, guard.Downsize)
+	// Step 8: Set up the token filters applied before every buy
+	filters := buildFilters(configurations, baseChain, baseClient, db)
+	log.Printf("Token filters: %s", filterNames(filters))
+	risk := cmd.NewRiskManager(configurations, db)
+	log.Printf("Risk limits: %+v", risk.Limits)
+	cooldowns := &engine.Cooldowns{
+		Cache:        redisCache,
+		LeaderWindow: configurations.LeaderCooldown,
+		TokenWindow:  configurations.TokenCooldown,
+		Mode:         configurations.CooldownMode,
+	}
+	if cooldowns.Mode != engine.CooldownSkip && cooldowns.Mode != engine.CooldownMerge {
+		log.Fatalf("Unknown cooldown mode: %s", cooldowns.Mode)
+	}
+	log.Printf("Cooldowns: leader %s, token %s, repeats %s", cooldowns.LeaderWindow, cooldowns.TokenWindow, cooldowns.Mode)
+	// Step 9: Trade from the follower wallets, or simulate fills in paper trading mode
+	var executor engine.Executor
+	if configurations.PaperTrading {
+		executor = cmd.NewPaperExecutor(configurations, baseChain, baseClient, db)
+		log.Printf("Paper trading: fills are simulated and recorded in the paper tables")
+	} else {
+		executor = cmd.NewLiveExecutor(baseChain, baseClient, router, db)
+	}
+	// Step 10: Watch the tracked leaders and copy their trades until shutdown
+	listener := evm.NewListener("base", baseClient, db)
+	if baseChain.WSURL != "" {
+		listener.WSURL = baseChain.WSURL
+		log.Printf("Subscribing to new heads on %s", baseChain.WSURL)
+	}
+	copyEngine := &engine.Engine{
+		Chain:         "base",
+		Client:        baseClient,
+		Executor:      executor,
+		DB:            cmd.TradeDatabase(configurations, db),
+		Listener:      listener,
+		Evaluator:     &engine.Evaluator{DB: db, Default: engine.DefaultStrategy},
+		Guard:         guard,
+		Filters:       filters,
+		Risk:          risk,
+		Cooldowns:     cooldowns,
+		Tokens:        tokens,
+		UniswapRouter: baseChain.UniswapV2Router(),
+		Factory:       baseChain.UniswapV2Factory(),
+		WETH:          baseChain.WETH,
+		DefaultWallet: common.HexToAddress(configurations.PublicKey),
+		MaxSignalAge:  configurations.MaxSignalAge,
+		Explorer:      baseChain.Explorer(),
+	}
+	go func() {
+		if err := copyEngine.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatalf("Engine stopped: %v", err)
+		}
+	}()
+	if err := listener.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Listener stopped: %v", err)
+	}
+}
+
+// buildFilters assembles the token filters enabled by the configuration, cheapest first.
+func buildFilters(configurations *cmd.Config, chain *evm.ChainConfig, client *ethclient.Client, db *database.Database) engine.FilterPipeline {
+	creations := &engine.Creations{Client: client}
+	filters := engine.FilterPipeline{
+		&engine.ListFilter{Chain: "base", DB: db, Creations: creations, AllowlistOnly: configurations.AllowlistOnly},
+		&engine.ERC20Filter{Client: client},
+		&engine.RiskFilter{Client: client, MaxScore: configurations.MaxRiskScore},
+	}
+	if configurations.MinTokenAge > 0 {
+		filters = append(filters, &engine.MinAgeFilter{
+			Client:    client,
+			Factory:   chain.UniswapV2Factory(),
+			WETH:      chain.WETH,
+			Creations: creations,
+			MinAge:    configurations.MinTokenAge,
+		})
+	}
+	if configurations.MinHolders > 0 && configurations.HoldersAPIURL != "" {
+		filters = append(filters, &engine.HolderCountFilter{
+			APIURL:     configurations.HoldersAPIURL,
+			MinHolders: uint64(configurations.MinHolders),
+		})
+	}
+	return filters
+}
+
+func filterNames(filters engine.FilterPipeline) string {
+	names := make([]string, len(filters))
+	for i, filter := range filters {
+		names[i] = filter.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// buildPriceSource assembles the configured ETH/USD sources into a fallback chain, in order.
+func buildPriceSource(configurations *cmd.Config, chain *evm.ChainConfig, client *ethclient.Client) evm.PriceSource {
+	fallback := &evm.FallbackPriceSource{}
+	for _, name := range strings.Split(configurations.PriceSources, ",") {
+		switch strings.TrimSpace(name) {
+		case "chainlink":
+			fallback.Sources = append(fallback.Sources, &evm.ChainlinkPriceSource{
+				Client:     client,
+				Aggregator: common.HexToAddress(configurations.EthUsdFeed),
+				MaxAge:     configurations.PriceMaxAge,
+			})
+		case "pool":
+			fallback.Sources = append(fallback.Sources, &evm.PoolPriceSource{
+				Client:       client,
+				Factory:      chain.UniswapV2Factory(),
+				WETH:         chain.WETH,
+				USDC:         common.HexToAddress(configurations.UsdcBaseAddress),
+				USDCDecimals: 6,
+				MaxAge:       configurations.PriceMaxAge,
+			})
+		case "coingecko":
+			fallback.Sources = append(fallback.Sources, &evm.CoinGeckoPriceSource{})
+		case "":
+		default:
+			log.Fatalf("Unknown price source: %s", name)
+		}
+	}
+	return fallback
 }
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// LoadSigners unlocks the keystore and returns a signer for every wallet we can send from:
+// keystore wallets, configured remote signer accounts and, with a mnemonic, derived sub-wallets.
+func LoadSigners(config *Config, db *database.Database) ([]evm.Signer, error) {
+	keys := evm.OpenKeyStore(config.KeystoreDir)
+	if len(keys.Accounts()) > 0 {
+		passphrase, err := ReadPassphrase(config, "Keystore passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if err := keys.UnlockAll(passphrase); err != nil {
+			return nil, err
+		}
+		log.Printf("Unlocked %d follower wallets", len(keys.Accounts()))
+	}
+
+	var signers []evm.Signer
+	for _, address := range keys.Accounts() {
+		signers = append(signers, &evm.KeystoreSigner{Keys: keys, Account: address})
+	}
+
+	if config.RemoteSignerURL != "" {
+		for _, account := range strings.Split(config.RemoteSigners, ",") {
+			if account = strings.TrimSpace(account); account == "" {
+				continue
+			}
+			if !common.IsHexAddress(account) {
+				return nil, fmt.Errorf("invalid remote signer account: %s", account)
+			}
+			signers = append(signers, &evm.RemoteSigner{
+				URL:     config.RemoteSignerURL,
+				Account: common.HexToAddress(account),
+				Method:  config.RemoteSignerMethod,
+			})
+		}
+	}
+
+	if config.MnemonicFile != "" {
+		subWallets, err := deriveSubWalletSigners(config, db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive sub-wallets: %v", err)
+		}
+		signers = append(signers, subWallets...)
+	}
+
+	return signers, nil
+}
+
+// deriveSubWalletSigners re-derives the keys of every stored sub-wallet from the mnemonic.
+func deriveSubWalletSigners(config *Config, db *database.Database) ([]evm.Signer, error) {
+	mnemonic, err := ReadMnemonic(config)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := evm.NewHDWallet(mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+
+	subWallets, err := db.ListSubWallets(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var signers []evm.Signer
+	for _, subWallet := range subWallets {
+		key, address, err := wallet.Derive(subWallet.DerivationIndex)
+		if err != nil {
+			return nil, err
+		}
+		if address != common.HexToAddress(subWallet.Address) {
+			return nil, fmt.Errorf("sub-wallet %d derives to %s, expected %s", subWallet.DerivationIndex, address.Hex(), subWallet.Address)
+		}
+		signers = append(signers, evm.NewKeySigner(key))
+	}
+	return signers, nil
+}
+
+// ChainConfigs returns the chains listed in CHAINS_FILE or, without one, Base configured from
+// the environment with BASE_RPC as a comma separated list of endpoints, RPC_TIMEOUT as its
+// call timeout and, optionally, UNIVERSAL_BASE_ROUTER as the Universal Router to sell through.
+func ChainConfigs(config *Config) ([]*evm.ChainConfig, error) {
+	if config.ChainsFile != "" {
+		return evm.LoadChainConfigs(config.ChainsFile)
+	}
+
+	chainID, ok := new(big.Int).SetString(config.ChainID, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid CHAIN_ID: %q", config.ChainID)
+	}
+	chain := &evm.ChainConfig{
+		Name:        "base",
+		ChainID:     chainID,
+		RPCURLs:     evm.SplitRPCURLs(config.BaseRPC),
+		WSURL:       config.BaseWS,
+		WETH:        common.HexToAddress(config.WethBaseAddress),
+		Routers:     map[string]common.Address{evm.DEXUniswapV2: common.HexToAddress(config.UniswapBaseRouter)},
+		Factories:   map[string]common.Address{evm.DEXUniswapV2: common.HexToAddress(config.UniswapBaseFactory)},
+		CallTimeout: evm.Duration(config.RPCTimeout),
+	}
+	if config.UniversalRouter != "" {
+		chain.Routers[evm.RouterUniversal] = common.HexToAddress(config.UniversalRouter)
+	}
+	return []*evm.ChainConfig{chain}, nil
+}
+
+// BaseChain returns the configuration of Base.
+func BaseChain(config *Config) (*evm.ChainConfig, error) {
+	chains, err := ChainConfigs(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		if chain.Name == "base" {
+			return chain, nil
+		}
+	}
+	return nil, fmt.Errorf("no base chain configured")
+}
+
+// DialBase connects to Base through every configured endpoint, health checking a pool of them
+// until ctx is cancelled.
+func DialBase(ctx context.Context, config *Config) (*ethclient.Client, error) {
+	chain, err := BaseChain(config)
+	if err != nil {
+		return nil, err
+	}
+	return evm.DialRPC(ctx, chain.Endpoints(), chain.Timeout())
+}
+
+// NewBaseRouter connects a MultiChainRouter to the configured chains, which must include Base,
+// with the given signers. Pooled endpoints are health checked until ctx is cancelled.
+func NewBaseRouter(ctx context.Context, config *Config, signers []evm.Signer) (*evm.MultiChainRouter, error) {
+	chains, err := ChainConfigs(config)
+	if err != nil {
+		return nil, err
+	}
+	router, err := evm.NewMultiChainRouter(ctx, chains, signers)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := router.Chain("base"); err != nil {
+		return nil, fmt.Errorf("no base chain configured: %v", err)
+	}
+
+	if config.PrivateRPCURL != "" {
+		router.AddBroadcaster(&evm.PrivateRPCBroadcaster{URL: config.PrivateRPCURL})
+	}
+	if config.BundleRPCURL != "" {
+		bundles := &evm.BundleBroadcaster{URL: config.BundleRPCURL, Blocks: uint64(config.BundleBlocks)}
+		if config.BundleAuthKeyFile != "" {
+			bundles.AuthKey, err = crypto.LoadECDSA(config.BundleAuthKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load bundle auth key: %v", err)
+			}
+		}
+		router.AddBroadcaster(bundles)
+	}
+	return router, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: strategies <command>
+
+commands:
+  add <name> [key=value ...]     create a strategy from the defaults
+  set <name> key=value ...       change a strategy
+  list                           list strategies
+  assign <leader> <name>         use a strategy for a leader on Base
+
+keys:
+  sizing (fixed|proportional), fixed_eth, proportional_pct, max_eth_per_trade,
+  max_position_eth, slippage_pct, allowed_tokens, mirror_exits, take_profit_pct,
+  stop_loss_pct, delay_ms, broadcast (public|private|bundle)`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "list":
+		strategies, err := db.ListStrategies(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list strategies: %v", err)
+		}
+		for _, s := range strategies {
+			fmt.Printf("%s\tsizing=%s fixed=%g pct=%g max_trade=%g max_position=%g slippage=%g mirror_exits=%v tp=%g sl=%g delay_ms=%d broadcast=%s allowed=%s\n",
+				s.Name, s.SizingMode, s.FixedETH, s.ProportionalPct, s.MaxETHPerTrade, s.MaxPositionETH,
+				s.SlippagePct, s.MirrorExits, s.TakeProfitPct, s.StopLossPct, s.DelayMs, s.Broadcast, s.AllowedTokens)
+		}
+	case "add":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		strategy := engine.DefaultStrategy
+		strategy.Name = os.Args[2]
+		applySettings(&strategy, os.Args[3:])
+		if err := db.CreateStrategy(ctx, &strategy); err != nil {
+			log.Fatalf("Failed to create strategy: %v", err)
+		}
+	case "set":
+		if len(os.Args) < 4 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		strategy, err := db.GetStrategyByName(ctx, os.Args[2])
+		if err != nil {
+			log.Fatalf("Failed to load strategy %s: %v", os.Args[2], err)
+		}
+		applySettings(&strategy, os.Args[3:])
+		if err := db.UpdateStrategy(ctx, &strategy); err != nil {
+			log.Fatalf("Failed to update strategy: %v", err)
+		}
+	case "assign":
+		if len(os.Args) < 4 || !common.IsHexAddress(os.Args[2]) {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		strategy, err := db.GetStrategyByName(ctx, os.Args[3])
+		if err != nil {
+			log.Fatalf("Failed to load strategy %s: %v", os.Args[3], err)
+		}
+		if err := db.AssignStrategy(ctx, "base", common.HexToAddress(os.Args[2]).Hex(), strategy.ID); err != nil {
+			log.Fatalf("Failed to assign strategy: %v", err)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+func applySettings(strategy *database.Strategy, settings []string) {
+	for _, setting := range settings {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			log.Fatalf("Invalid setting %q, expected key=value", setting)
+		}
+
+		var err error
+		switch key {
+		case "sizing":
+			if value != database.SizingFixed && value != database.SizingProportional {
+				log.Fatalf("Invalid sizing %q", value)
+			}
+			strategy.SizingMode = value
+		case "fixed_eth":
+			strategy.FixedETH, err = strconv.ParseFloat(value, 64)
+		case "proportional_pct":
+			strategy.ProportionalPct, err = strconv.ParseFloat(value, 64)
+		case "max_eth_per_trade":
+			strategy.MaxETHPerTrade, err = strconv.ParseFloat(value, 64)
+		case "max_position_eth":
+			strategy.MaxPositionETH, err = strconv.ParseFloat(value, 64)
+		case "slippage_pct":
+			strategy.SlippagePct, err = strconv.ParseFloat(value, 64)
+		case "allowed_tokens":
+			strategy.AllowedTokens = value
+		case "mirror_exits":
+			strategy.MirrorExits, err = strconv.ParseBool(value)
+		case "take_profit_pct":
+			strategy.TakeProfitPct, err = strconv.ParseFloat(value, 64)
+		case "stop_loss_pct":
+			strategy.StopLossPct, err = strconv.ParseFloat(value, 64)
+		case "delay_ms":
+			strategy.DelayMs, err = strconv.ParseInt(value, 10, 64)
+		case "broadcast":
+			if value != evm.BroadcastPublic && value != evm.BroadcastPrivate && value != evm.BroadcastBundle {
+				log.Fatalf("Invalid broadcast %q", value)
+			}
+			strategy.Broadcast = value
+		default:
+			log.Fatalf("Unknown setting %q", key)
+		}
+		if err != nil {
+			log.Fatalf("Invalid value for %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: tokenlists <command>
+
+commands:
+  allow <token|deployer> <address> [reason]  allowlist a token or every token from a deployer
+  deny <token|deployer> <address> [reason]   denylist a token or every token from a deployer
+  remove <token|deployer> <address>          remove a token or deployer from its list
+  list                                       list allowlisted and denylisted addresses`
+
+const chain = "base"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+
+	if os.Args[1] == "list" {
+		entries, err := db.ListTokenListEntries(ctx, chain)
+		if err != nil {
+			log.Fatalf("Failed to list token lists: %v", err)
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s\t%s\t%s\t%s\n", entry.List, entry.Kind, entry.Address, entry.Reason)
+		}
+		return
+	}
+
+	if len(os.Args) < 4 || (os.Args[2] != database.ListKindToken && os.Args[2] != database.ListKindDeployer) || !common.IsHexAddress(os.Args[3]) {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	kind := os.Args[2]
+	address := common.HexToAddress(os.Args[3]).Hex()
+
+	switch os.Args[1] {
+	case database.ListAllow, database.ListDeny:
+		err = db.SaveTokenListEntry(ctx, database.TokenListEntry{
+			Chain:   chain,
+			Address: address,
+			Kind:    kind,
+			List:    os.Args[1],
+			Reason:  strings.Join(os.Args[4:], " "),
+		})
+	case "remove":
+		err = db.DeleteTokenListEntry(ctx, chain, address, kind)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("Failed to %s %s %s: %v", os.Args[1], kind, address, err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+	"copytrader/internal/treasury"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const usage = `usage: treasury <command>
+
+commands:
+  balances                        show ETH balances of the master and managed wallets
+  topup <threshold> <target>      fund wallets below threshold ETH up to target ETH
+  sweep <keep>                    send everything above keep ETH back to the master wallet
+  rebalance <target>              move every managed wallet to target ETH`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+	signers, err := cmd.LoadSigners(configurations, db)
+	if err != nil {
+		log.Fatalf("Failed to load signers: %v", err)
+	}
+	router, err := cmd.NewBaseRouter(ctx, configurations, signers)
+	if err != nil {
+		log.Fatalf("Failed to set up router: %v", err)
+	}
+
+	master := common.HexToAddress(configurations.PublicKey)
+	t := &treasury.Treasury{
+		Chain:  "base",
+		Router: router,
+		DB:     db,
+		Master: master,
+	}
+	for _, signer := range signers {
+		if signer.Address() != master {
+			t.Wallets = append(t.Wallets, signer.Address())
+		}
+	}
+
+	var transfers []database.Transfer
+
+	switch os.Args[1] {
+	case "balances":
+		balances, err := t.Balances(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read balances: %v", err)
+		}
+		for _, balance := range balances {
+			fmt.Printf("%s\t%s ETH\n", balance.Address.Hex(), evm.FormatUnits(balance.Wei, 18))
+		}
+		return
+	case "topup":
+		if len(os.Args) < 4 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		transfers, err = t.TopUp(ctx, parseETH(os.Args[2]), parseETH(os.Args[3]))
+	case "sweep":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		transfers, err = t.Sweep(ctx, parseETH(os.Args[2]))
+	case "rebalance":
+		if len(os.Args) < 3 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		target := parseETH(os.Args[2])
+		targets := make(map[common.Address]*big.Int)
+		for _, wallet := range t.Wallets {
+			targets[wallet] = target
+		}
+		transfers, err = t.Rebalance(ctx, targets)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	for _, transfer := range transfers {
+		fmt.Printf("%s\t%s -> %s\t%s wei\t%s\n", transfer.Kind, transfer.FromAddress, transfer.ToAddress, transfer.AmountWei, transfer.Hash)
+	}
+	if err != nil {
+		log.Fatalf("Treasury %s failed: %v", os.Args[1], err)
+	}
+}
+
+func parseETH(value string) *big.Int {
+	amount, err := evm.ParseUnits(value, 18)
+	if err != nil {
+		log.Fatalf("Invalid ETH amount: %v", err)
+	}
+	return amount
+}
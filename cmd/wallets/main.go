@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"copytrader/cmd"
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const usage = `usage: wallets <command>
+
+commands:
+  mnemonic                 generate a new BIP-39 mnemonic
+  derive [count]           derive the next count follower sub-wallets (default 1)
+  list                     list the derived sub-wallets
+  label <index> <label>    label a sub-wallet`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	if os.Args[1] == "mnemonic" {
+		mnemonic, err := evm.NewMnemonic()
+		if err != nil {
+			log.Fatalf("Failed to generate mnemonic: %v", err)
+		}
+		fmt.Println(mnemonic)
+		return
+	}
+
+	configurations := cmd.LoadConfig()
+	db, err := database.NewDatabase(configurations.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.MigrateDB(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "derive":
+		count := 1
+		if len(os.Args) > 2 {
+			count, err = strconv.Atoi(os.Args[2])
+			if err != nil || count < 1 {
+				log.Fatalf("Invalid count: %s", os.Args[2])
+			}
+		}
+
+		mnemonic, err := cmd.ReadMnemonic(configurations)
+		if err != nil {
+			log.Fatalf("Failed to read mnemonic: %v", err)
+		}
+		wallet, err := evm.NewHDWallet(mnemonic, "")
+		if err != nil {
+			log.Fatalf("Failed to load HD wallet: %v", err)
+		}
+
+		next, err := db.NextSubWalletIndex(ctx)
+		if err != nil {
+			log.Fatalf("Failed to find next wallet index: %v", err)
+		}
+		for index := next; index < next+uint32(count); index++ {
+			_, address, err := wallet.Derive(index)
+			if err != nil {
+				log.Fatalf("Failed to derive wallet %d: %v", index, err)
+			}
+			if err := db.CreateSubWallet(ctx, database.SubWallet{DerivationIndex: index, Address: address.Hex()}); err != nil {
+				log.Fatalf("Failed to save wallet %d: %v", index, err)
+			}
+			fmt.Printf("%d\t%s\t%s\n", index, address.Hex(), evm.SubWalletPath(index))
+		}
+	case "list":
+		wallets, err := db.ListSubWallets(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list wallets: %v", err)
+		}
+		for _, wallet := range wallets {
+			fmt.Printf("%d\t%s\t%s\n", wallet.DerivationIndex, wallet.Address, wallet.Label)
+		}
+	case "label":
+		if len(os.Args) < 4 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		index, err := strconv.ParseUint(os.Args[2], 10, 32)
+		if err != nil {
+			log.Fatalf("Invalid index: %s", os.Args[2])
+		}
+		if err := db.LabelSubWallet(ctx, uint32(index), strings.Join(os.Args[3:], " ")); err != nil {
+			log.Fatalf("Failed to label wallet %d: %v", index, err)
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
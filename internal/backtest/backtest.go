@@ -0,0 +1,349 @@
+package backtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/engine"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Backtester replays a leader's historical swaps through a copy strategy. Fills are priced
+// from the token/WETH pair reserves at past blocks, so it needs an archive node.
+type Backtester struct {
+	Chain    string
+	Client   *ethclient.Client
+	Factory  common.Address
+	WETH     common.Address
+	Strategy database.Strategy
+	// Latency is how many blocks after the leader our copy lands. Zero copies in the
+	// leader's own block, right behind the leader.
+	Latency uint64
+	BuyGas  uint64
+	SellGas uint64
+	// GasPrice in wei, nil uses the base fee of the block each copy lands in.
+	GasPrice *big.Int
+	LogRange uint64
+}
+
+// Trade is the simulated copy of one leader swap. Amounts are in wei or token base units.
+type Trade struct {
+	Signal *evm.Signal
+	// Block is the block the copy is filled in.
+	Block uint64
+	// ETHAmount is the ETH spent on a buy or received from a sell, gas included.
+	ETHAmount   *big.Int
+	TokenAmount *big.Int
+	// PnL is set on sells that close a position.
+	PnL     *big.Int
+	Skipped string
+	// Exit is the take profit or stop loss that closed a position on its own, without a
+	// leader sell. Signal then describes the exit rather than a swap of the leader's.
+	Exit string
+}
+
+// Summary aggregates a backtest. Amounts are in wei.
+type Summary struct {
+	Signals     int
+	Buys        int
+	Sells       int
+	Skipped     int
+	Wins        int
+	Losses      int
+	Realised    *big.Int
+	OpenCost    *big.Int
+	OpenValue   *big.Int
+	MaxDrawdown *big.Int
+}
+
+// WinRate returns the share of closed positions that made money, in percent.
+func (s *Summary) WinRate() float64 {
+	if s.Wins+s.Losses == 0 {
+		return 0
+	}
+	return float64(s.Wins) / float64(s.Wins+s.Losses) * 100
+}
+
+// Total returns the realised PnL plus open positions marked to market.
+func (s *Summary) Total() *big.Int {
+	total := new(big.Int).Add(s.Realised, s.OpenValue)
+	return total.Sub(total, s.OpenCost)
+}
+
+type position struct {
+	cost   *big.Int
+	tokens *big.Int
+	// block is where the last buy into the position was filled.
+	block uint64
+}
+
+// Run replays the leader's swaps between fromBlock and toBlock. Before each swap, and at
+// toBlock, open positions are checked against the strategy's take profit and stop loss.
+// Positions still open at toBlock are marked to market against the reserves at toBlock.
+func (b *Backtester) Run(ctx context.Context, leader common.Address, fromBlock, toBlock uint64) ([]*Trade, *Summary, error) {
+	signals, err := evm.FindLeaderSwaps(ctx, b.Client, b.Chain, leader, fromBlock, toBlock, b.LogRange)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary := &Summary{
+		Signals:     len(signals),
+		Realised:    new(big.Int),
+		OpenCost:    new(big.Int),
+		OpenValue:   new(big.Int),
+		MaxDrawdown: new(big.Int),
+	}
+	positions := make(map[common.Address]*position)
+	peak := new(big.Int)
+
+	var trades []*Trade
+	record := func(trade *Trade) {
+		summary.Sells++
+		summary.Realised.Add(summary.Realised, trade.PnL)
+		if trade.PnL.Sign() > 0 {
+			summary.Wins++
+		} else {
+			summary.Losses++
+		}
+		if summary.Realised.Cmp(peak) > 0 {
+			peak.Set(summary.Realised)
+		}
+		if drawdown := new(big.Int).Sub(peak, summary.Realised); drawdown.Cmp(summary.MaxDrawdown) > 0 {
+			summary.MaxDrawdown = drawdown
+		}
+	}
+	exitAt := func(block uint64) error {
+		exits, err := b.checkExits(ctx, leader, block, positions)
+		for _, exit := range exits {
+			trades = append(trades, exit)
+			record(exit)
+		}
+		return err
+	}
+
+	for _, signal := range signals {
+		// Exits are decided on the state right before the leader's swap
+		if signal.BlockNumber > 0 {
+			if err := exitAt(signal.BlockNumber - 1); err != nil {
+				return trades, nil, err
+			}
+		}
+
+		trade := &Trade{Signal: signal, Block: signal.BlockNumber + b.Latency}
+		trades = append(trades, trade)
+
+		switch signal.Side {
+		case evm.SideBuy:
+			err = b.buy(ctx, trade, positions)
+		case evm.SideSell:
+			err = b.sell(ctx, trade, positions)
+		}
+		if err != nil {
+			return trades, nil, err
+		}
+
+		if trade.Skipped != "" {
+			summary.Skipped++
+			continue
+		}
+		if signal.Side == evm.SideBuy {
+			summary.Buys++
+			continue
+		}
+		record(trade)
+	}
+	if err := exitAt(toBlock); err != nil {
+		return trades, nil, err
+	}
+
+	end := new(big.Int).SetUint64(toBlock)
+	for token, open := range positions {
+		summary.OpenCost.Add(summary.OpenCost, open.cost)
+		reserves, err := evm.GetPairReserves(ctx, b.Client, b.Factory, token, b.WETH, end)
+		if err != nil {
+			continue
+		}
+		summary.OpenValue.Add(summary.OpenValue, evm.GetAmountOut(open.tokens, reserves.ReserveToken, reserves.ReserveWETH))
+	}
+
+	return trades, summary, nil
+}
+
+func (b *Backtester) buy(ctx context.Context, trade *Trade, positions map[common.Address]*position) error {
+	signal := trade.Signal
+
+	held := new(big.Int)
+	if open := positions[signal.Token]; open != nil {
+		held = open.cost
+	}
+	decision := engine.EvaluateBuy(b.Strategy, signal, held)
+	if !decision.Copy {
+		trade.Skipped = decision.Reason
+		return nil
+	}
+
+	// We quote once the leader's block is known, or from the block before ours if we land
+	// later, and fill in ours, so the strategy's slippage tolerance decides whether the copy
+	// would have reverted.
+	quoteBlock := trade.Block - 1
+	if quoteBlock < signal.BlockNumber {
+		quoteBlock = signal.BlockNumber
+	}
+	quoteReserves, err := b.reserves(ctx, signal.Token, quoteBlock)
+	if err != nil {
+		trade.Skipped = err.Error()
+		return nil
+	}
+	fillReserves, err := b.reserves(ctx, signal.Token, trade.Block)
+	if err != nil {
+		trade.Skipped = err.Error()
+		return nil
+	}
+
+	quote := evm.GetAmountOut(decision.AmountIn, quoteReserves.ReserveWETH, quoteReserves.ReserveToken)
+	received := evm.GetAmountOut(decision.AmountIn, fillReserves.ReserveWETH, fillReserves.ReserveToken)
+	if minimum := withSlippage(quote, decision.Slippage); received.Cmp(minimum) < 0 || received.Sign() == 0 {
+		trade.Skipped = fmt.Sprintf("would revert: filled %s tokens, minimum %s", received, minimum)
+		return nil
+	}
+
+	gas, err := b.gasCost(ctx, trade.Block, b.BuyGas)
+	if err != nil {
+		return err
+	}
+
+	trade.ETHAmount = new(big.Int).Add(decision.AmountIn, gas)
+	trade.TokenAmount = received
+
+	open := positions[signal.Token]
+	if open == nil {
+		open = &position{cost: new(big.Int), tokens: new(big.Int)}
+		positions[signal.Token] = open
+	}
+	open.cost.Add(open.cost, trade.ETHAmount)
+	open.tokens.Add(open.tokens, received)
+	open.block = trade.Block
+	return nil
+}
+
+func (b *Backtester) sell(ctx context.Context, trade *Trade, positions map[common.Address]*position) error {
+	signal := trade.Signal
+
+	open := positions[signal.Token]
+	if open == nil {
+		trade.Skipped = "no position to sell"
+		return nil
+	}
+	decision := engine.EvaluateSell(b.Strategy, signal)
+	if !decision.Copy {
+		trade.Skipped = decision.Reason
+		return nil
+	}
+
+	reserves, err := b.reserves(ctx, signal.Token, trade.Block)
+	if err != nil {
+		trade.Skipped = err.Error()
+		return nil
+	}
+	return b.close(ctx, trade, reserves, positions)
+}
+
+// checkExits sells the positions whose value at block has reached the strategy's take profit
+// or stop loss, filling the exits in that same block.
+func (b *Backtester) checkExits(ctx context.Context, leader common.Address, block uint64, positions map[common.Address]*position) ([]*Trade, error) {
+	if b.Strategy.TakeProfitPct <= 0 && b.Strategy.StopLossPct <= 0 {
+		return nil, nil
+	}
+
+	var exits []*Trade
+	for token, open := range positions {
+		if block < open.block || open.cost.Sign() == 0 {
+			continue
+		}
+		reserves, err := b.reserves(ctx, token, block)
+		if err != nil {
+			continue
+		}
+		value := evm.GetAmountOut(open.tokens, reserves.ReserveToken, reserves.ReserveWETH)
+		ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(value), new(big.Float).SetInt(open.cost)).Float64()
+		exit, reason := engine.ShouldExit(b.Strategy, (ratio-1)*100)
+		if !exit {
+			continue
+		}
+
+		trade := &Trade{
+			Signal: &evm.Signal{Chain: b.Chain, Leader: leader, Side: evm.SideSell, Token: token, BlockNumber: block},
+			Block:  block,
+			Exit:   reason,
+		}
+		if err := b.close(ctx, trade, reserves, positions); err != nil {
+			return exits, err
+		}
+		exits = append(exits, trade)
+	}
+	sort.Slice(exits, func(i, j int) bool {
+		return bytes.Compare(exits[i].Signal.Token.Bytes(), exits[j].Signal.Token.Bytes()) < 0
+	})
+	return exits, nil
+}
+
+// close sells a whole position at the given reserves, in the trade's block.
+func (b *Backtester) close(ctx context.Context, trade *Trade, reserves *evm.PairReserves, positions map[common.Address]*position) error {
+	token := trade.Signal.Token
+	open := positions[token]
+
+	gas, err := b.gasCost(ctx, trade.Block, b.SellGas)
+	if err != nil {
+		return err
+	}
+
+	received := evm.GetAmountOut(open.tokens, reserves.ReserveToken, reserves.ReserveWETH)
+	received.Sub(received, gas)
+
+	trade.ETHAmount = received
+	trade.TokenAmount = open.tokens
+	trade.PnL = new(big.Int).Sub(received, open.cost)
+	delete(positions, token)
+	return nil
+}
+
+func (b *Backtester) reserves(ctx context.Context, token common.Address, block uint64) (*evm.PairReserves, error) {
+	reserves, err := evm.GetPairReserves(ctx, b.Client, b.Factory, token, b.WETH, new(big.Int).SetUint64(block))
+	if errors.Is(err, evm.ErrPairNotFound) {
+		return nil, fmt.Errorf("no WETH pair for %s", token.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no reserves at block %d: %v", block, err)
+	}
+	return reserves, nil
+}
+
+func (b *Backtester) gasCost(ctx context.Context, block uint64, gas uint64) (*big.Int, error) {
+	gasPrice := b.GasPrice
+	if gasPrice == nil {
+		header, err := b.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get header %d: %v", block, err)
+		}
+		gasPrice = header.BaseFee
+		if gasPrice == nil {
+			gasPrice = new(big.Int)
+		}
+	}
+	return new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas)), nil
+}
+
+// withSlippage returns amount reduced by slippage percent.
+func withSlippage(amount *big.Int, slippage float64) *big.Int {
+	scaled := new(big.Float).Mul(new(big.Float).SetInt(amount), big.NewFloat(1-slippage/100))
+	result, _ := scaled.Int(nil)
+	return result
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrCacheMiss is returned when a key is not present in the cache.
+var ErrCacheMiss = errors.New("cache miss")
+
+type Cache struct {
+	Client *redis.Client
+}
+
+// NewCache connects to Redis. redisURL may be a redis:// URL or a plain host:port address.
+func NewCache(redisURL string) (*Cache, error) {
+	opts := &redis.Options{Addr: redisURL}
+	if strings.Contains(redisURL, "://") {
+		parsed, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redis URL: %v", err)
+		}
+		opts = parsed
+	}
+
+	return &Cache{
+		Client: redis.NewClient(opts),
+	}, nil
+}
+
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.Client.Ping(ctx).Err()
+}
+
+// GetJSON loads the value stored at key into dest.
+func (c *Cache) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	raw, err := c.Client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dest)
+}
+
+// SetJSON stores value at key as JSON, expiring after ttl (0 keeps it forever).
+func (c *Cache) SetJSON(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.Client.Set(ctx, key, raw, ttl).Err()
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Approval records a token allowance granted from one of our wallets, so it can be revoked.
+type Approval struct {
+	gorm.Model
+	Chain   string `gorm:"type:varchar(32);uniqueIndex:idx_approval;not null"`
+	Owner   string `gorm:"type:varchar(42);uniqueIndex:idx_approval;not null"`
+	Token   string `gorm:"type:varchar(42);uniqueIndex:idx_approval;not null"`
+	Spender string `gorm:"type:varchar(42);uniqueIndex:idx_approval;not null"`
+	TxHash  string `gorm:"type:varchar(66)"`
+}
+
+func (d *Database) SaveApproval(ctx context.Context, approval Approval) error {
+	return d.Client.WithContext(ctx).
+		Where(Approval{Chain: approval.Chain, Owner: approval.Owner, Token: approval.Token, Spender: approval.Spender}).
+		Assign(Approval{TxHash: approval.TxHash}).
+		FirstOrCreate(&approval).Error
+}
+
+func (d *Database) ListApprovals(ctx context.Context, chain string) ([]Approval, error) {
+	var approvals []Approval
+	err := d.Client.WithContext(ctx).Where("chain = ?", chain).Order("owner, created_at").Find(&approvals).Error
+	return approvals, err
+}
+
+func (d *Database) DeleteApproval(ctx context.Context, chain, owner, token, spender string) error {
+	return d.Client.WithContext(ctx).Unscoped().
+		Where("chain = ? AND owner = ? AND token = ? AND spender = ?", chain, owner, token, spender).
+		Delete(&Approval{}).Error
+}
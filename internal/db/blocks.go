@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProcessedBlock is the hash of a block the listener has scanned, kept to detect reorgs.
+type ProcessedBlock struct {
+	ID     uint   `gorm:"primarykey"`
+	Chain  string `gorm:"type:varchar(32);uniqueIndex:idx_processed_block;not null"`
+	Number uint64 `gorm:"uniqueIndex:idx_processed_block;not null"`
+	Hash   string `gorm:"type:varchar(66);not null"`
+}
+
+func (d *Database) SaveProcessedBlock(ctx context.Context, block ProcessedBlock) error {
+	return d.Client.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain"}, {Name: "number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hash"}),
+	}).Create(&block).Error
+}
+
+// GetProcessedBlockHash returns the hash stored for a block, or an empty string if the block
+// has not been processed or was pruned.
+func (d *Database) GetProcessedBlockHash(ctx context.Context, chain string, number uint64) (string, error) {
+	var block ProcessedBlock
+	err := d.Client.WithContext(ctx).Where("chain = ? AND number = ?", chain, number).First(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return block.Hash, err
+}
+
+// ListProcessedBlocksFrom returns the stored blocks from a block number on, oldest first.
+func (d *Database) ListProcessedBlocksFrom(ctx context.Context, chain string, from uint64) ([]ProcessedBlock, error) {
+	var blocks []ProcessedBlock
+	err := d.Client.WithContext(ctx).Where("chain = ? AND number >= ?", chain, from).Order("number").Find(&blocks).Error
+	return blocks, err
+}
+
+// PruneProcessedBlocks deletes the stored blocks below a block number.
+func (d *Database) PruneProcessedBlocks(ctx context.Context, chain string, below uint64) error {
+	return d.Client.WithContext(ctx).Where("chain = ? AND number < ?", chain, below).Delete(&ProcessedBlock{}).Error
+}
@@ -2,29 +2,142 @@ package database
 
 import (
 	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type BuyTransaction struct {
 	gorm.Model
-	ETHAmount       int
-	ContractAddress string `gorm:"type:varchar(42);unique;not null"`
+	// ETHAmount is the ETH spent in wei, as a decimal string.
+	ETHAmount       string `gorm:"type:varchar(78)"`
+	ContractAddress string `gorm:"type:varchar(42);index;not null"`
 	Ticker          string `gorm:"type:varchar(10);not null"`
 	Hash            string `gorm:"type:varchar(66);unique;not null"`
+	Chain           string `gorm:"type:varchar(32);index"`
+	Wallet          string `gorm:"type:varchar(42);index"`
+	Leader          string `gorm:"type:varchar(42);index"`
+	LeaderHash      string `gorm:"type:varchar(66)"`
+	// TokenAmount is the tokens received in base units, when known.
+	TokenAmount string `gorm:"type:varchar(78)"`
+
+	// Copy latency. LeaderBlock and LeaderTime are when the leader's swap was mined, DetectedAt
+	// when we saw it and BroadcastAt when our swap was sent. Block and BlockTime are when ours
+	// was mined, zero until it is.
+	LeaderBlock uint64
+	LeaderTime  time.Time
+	DetectedAt  time.Time
+	BroadcastAt time.Time
+	Block       uint64
+	BlockTime   time.Time
+	// LeaderPrice and FillPrice are the wei paid per token base unit by the leader and by us.
+	// PriceDiffPct is how much more we paid, in percent, and is negative when we paid less.
+	LeaderPrice  float64
+	FillPrice    float64
+	PriceDiffPct float64
+	// Reorged is set while the leader's swap or ours is missing from the chain after a reorg.
+	Reorged bool `gorm:"index"`
+	// Failed is set when our swap reverted or was never mined, so it holds no position.
+	Failed bool `gorm:"index"`
+}
+
+// PaperBuyTransaction is a simulated buy recorded in paper trading mode.
+type PaperBuyTransaction struct {
+	BuyTransaction
+}
+
+func (PaperBuyTransaction) TableName() string {
+	return "paper_buy_transactions"
+}
+
+// buys scopes a query to the live or paper buy table.
+func (d *Database) buys(ctx context.Context) *gorm.DB {
+	if d.Paper {
+		return d.Client.WithContext(ctx).Table(PaperBuyTransaction{}.TableName())
+	}
+	return d.Client.WithContext(ctx).Model(&BuyTransaction{})
 }
 
 func (d *Database) CreateBuyTransaction(ctx context.Context, txn BuyTransaction) error {
-	return d.Client.WithContext(ctx).Create(&txn).Error
+	return d.buys(ctx).Create(&txn).Error
 }
 
 func (d *Database) GetBuyTransactionByCA(ctx context.Context, CA string) (BuyTransaction, error) {
 	var txn BuyTransaction
-	err := d.Client.WithContext(ctx).Where("ContractAddress = ?", CA).First(&txn).Error
+	err := d.buys(ctx).Where("contract_address = ?", CA).First(&txn).Error
 	return txn, err
 }
 
 func (d *Database) GetBuyTransactionByHash(ctx context.Context, hash string) (BuyTransaction, error) {
 	var txn BuyTransaction
-	err := d.Client.WithContext(ctx).Where("Hash = ?", hash).First(&txn).Error
+	err := d.buys(ctx).Where("hash = ?", hash).First(&txn).Error
 	return txn, err
 }
+
+// UpdateBuyFill records where a buy was mined and what it paid compared to the leader.
+func (d *Database) UpdateBuyFill(ctx context.Context, txn BuyTransaction) error {
+	return d.buys(ctx).Where("hash = ?", txn.Hash).
+		Select("token_amount", "block", "block_time", "leader_price", "fill_price", "price_diff_pct").
+		Updates(&txn).Error
+}
+
+// SetBuyFailed marks a buy that reverted or was never mined.
+func (d *Database) SetBuyFailed(ctx context.Context, hash string) error {
+	return d.buys(ctx).Where("hash = ?", hash).Update("failed", true).Error
+}
+
+// SetBuyInclusion records the block a buy is included in after a reorg, or that it was dropped.
+func (d *Database) SetBuyInclusion(ctx context.Context, hash string, block uint64, reorged bool) error {
+	return d.buys(ctx).Where("hash = ?", hash).
+		Updates(map[string]interface{}{"block": block, "reorged": reorged}).Error
+}
+
+// ListReorgedBuyTransactions returns the buys on a chain currently marked as reorged, failed
+// ones aside.
+func (d *Database) ListReorgedBuyTransactions(ctx context.Context, chain string) ([]BuyTransaction, error) {
+	var txns []BuyTransaction
+	err := d.buys(ctx).Where("chain = ? AND reorged = ? AND failed = ?", chain, true, false).Order("created_at").Find(&txns).Error
+	return txns, err
+}
+
+// ListBuyTransactions returns every buy of a token made from a wallet, leaving aside failed
+// ones and those a reorg dropped.
+func (d *Database) ListBuyTransactions(ctx context.Context, wallet, CA string) ([]BuyTransaction, error) {
+	var txns []BuyTransaction
+	err := d.buys(ctx).Where("wallet = ? AND contract_address = ? AND failed = ? AND reorged = ?", wallet, CA, false, false).Order("created_at").Find(&txns).Error
+	return txns, err
+}
+
+// ListBuyTransactionsSince returns the buys made on a chain since the given time, failed ones
+// aside.
+func (d *Database) ListBuyTransactionsSince(ctx context.Context, chain string, since time.Time) ([]BuyTransaction, error) {
+	var txns []BuyTransaction
+	err := d.buys(ctx).Where("chain = ? AND created_at >= ? AND failed = ?", chain, since, false).Order("created_at").Find(&txns).Error
+	return txns, err
+}
+
+// CountBuyTransactionsSince counts the buys made on a chain since the given time.
+func (d *Database) CountBuyTransactionsSince(ctx context.Context, chain string, since time.Time) (int64, error) {
+	var count int64
+	err := d.buys(ctx).Where("chain = ? AND created_at >= ?", chain, since).Count(&count).Error
+	return count, err
+}
+
+// BuyPosition identifies tokens bought from a wallet on behalf of a leader.
+type BuyPosition struct {
+	Chain           string
+	Wallet          string
+	Leader          string
+	ContractAddress string
+}
+
+// ListBuyPositions returns every distinct wallet, leader and token we have bought, counting
+// only buys that did not fail.
+func (d *Database) ListBuyPositions(ctx context.Context) ([]BuyPosition, error) {
+	var positions []BuyPosition
+	err := d.buys(ctx).
+		Distinct("chain", "wallet", "leader", "contract_address").
+		Where("wallet <> '' AND failed = ?", false).
+		Scan(&positions).Error
+	return positions, err
+}
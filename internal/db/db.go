@@ -9,6 +9,8 @@ import (
 
 type Database struct {
 	Client *gorm.DB
+	// Paper points buy and sell queries at the paper trading tables.
+	Paper bool
 }
 
 func NewDatabase(databaseURL string) (*Database, error) {
@@ -37,3 +39,9 @@ func (d *Database) Ping(ctx context.Context) error {
 	}
 	return client.PingContext(ctx)
 }
+
+// PaperTrades returns a view of the database that records and reads paper trades instead of
+// live ones. Everything else, such as leaders and strategies, is shared.
+func (d *Database) PaperTrades() *Database {
+	return &Database{Client: d.Client, Paper: true}
+}
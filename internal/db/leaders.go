@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Leader is a wallet whose trades we copy.
+type Leader struct {
+	gorm.Model
+	Address    string `gorm:"type:varchar(42);uniqueIndex:idx_leader_chain_address;not null"`
+	Chain      string `gorm:"type:varchar(32);uniqueIndex:idx_leader_chain_address;not null"`
+	Label      string `gorm:"type:varchar(64)"`
+	Enabled    bool   `gorm:"not null"`
+	StrategyID *uint  `gorm:"index"`
+	// Wallet is the follower wallet that copies this leader; empty uses the default wallet.
+	Wallet  string    `gorm:"type:varchar(42)"`
+	AddedAt time.Time `gorm:"autoCreateTime"`
+	Notes   string
+}
+
+func (d *Database) CreateLeader(ctx context.Context, leader *Leader) error {
+	return d.Client.WithContext(ctx).Create(leader).Error
+}
+
+func (d *Database) GetLeader(ctx context.Context, chain, address string) (Leader, error) {
+	var leader Leader
+	err := d.Client.WithContext(ctx).Where("chain = ? AND address = ?", chain, address).First(&leader).Error
+	return leader, err
+}
+
+func (d *Database) ListLeaders(ctx context.Context, chain string) ([]Leader, error) {
+	var leaders []Leader
+	err := d.Client.WithContext(ctx).Where("chain = ?", chain).Order("added_at").Find(&leaders).Error
+	return leaders, err
+}
+
+func (d *Database) ListEnabledLeaders(ctx context.Context, chain string) ([]Leader, error) {
+	var leaders []Leader
+	err := d.Client.WithContext(ctx).Where("chain = ? AND enabled = ?", chain, true).Find(&leaders).Error
+	return leaders, err
+}
+
+func (d *Database) UpdateLeader(ctx context.Context, leader *Leader) error {
+	return d.Client.WithContext(ctx).Save(leader).Error
+}
+
+func (d *Database) SetLeaderEnabled(ctx context.Context, chain, address string, enabled bool) error {
+	result := d.Client.WithContext(ctx).Model(&Leader{}).Where("chain = ? AND address = ?", chain, address).Update("enabled", enabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (d *Database) DeleteLeader(ctx context.Context, chain, address string) error {
+	result := d.Client.WithContext(ctx).Unscoped().Where("chain = ? AND address = ?", chain, address).Delete(&Leader{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// LeaderScore is a wallet's result in the last discovery scan that included it.
+type LeaderScore struct {
+	gorm.Model
+	Chain   string `gorm:"type:varchar(32);uniqueIndex:idx_leader_score_chain_address;not null"`
+	Address string `gorm:"type:varchar(42);uniqueIndex:idx_leader_score_chain_address;not null"`
+	Buys    int    `gorm:"not null"`
+	Sells   int    `gorm:"not null"`
+	Wins    int    `gorm:"not null"`
+	Losses  int    `gorm:"not null"`
+	// RealisedETH is the ETH gained or lost on tokens bought and sold inside the scanned window.
+	RealisedETH float64 `gorm:"not null"`
+	// WinRate is the share of sells that made money, in percent.
+	WinRate       float64 `gorm:"not null"`
+	AvgHoldBlocks float64 `gorm:"not null"`
+	Score         float64 `gorm:"index;not null"`
+	FromBlock     uint64  `gorm:"not null"`
+	ToBlock       uint64  `gorm:"not null"`
+}
+
+// SaveLeaderScores inserts or replaces the scores of the given wallets.
+func (d *Database) SaveLeaderScores(ctx context.Context, scores []LeaderScore) error {
+	return d.Client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, score := range scores {
+			err := tx.Where(LeaderScore{Chain: score.Chain, Address: score.Address}).
+				// A map so that zero counts overwrite the previous scan's
+				Assign(map[string]interface{}{
+					"buys":            score.Buys,
+					"sells":           score.Sells,
+					"wins":            score.Wins,
+					"losses":          score.Losses,
+					"realised_eth":    score.RealisedETH,
+					"win_rate":        score.WinRate,
+					"avg_hold_blocks": score.AvgHoldBlocks,
+					"score":           score.Score,
+					"from_block":      score.FromBlock,
+					"to_block":        score.ToBlock,
+				}).
+				FirstOrCreate(&score).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *Database) GetLeaderScore(ctx context.Context, chain, address string) (LeaderScore, error) {
+	var score LeaderScore
+	err := d.Client.WithContext(ctx).Where("chain = ? AND address = ?", chain, address).First(&score).Error
+	return score, err
+}
+
+// ListLeaderScores returns the highest scoring wallets first, at most limit of them.
+func (d *Database) ListLeaderScores(ctx context.Context, chain string, limit int) ([]LeaderScore, error) {
+	var scores []LeaderScore
+	err := d.Client.WithContext(ctx).Where("chain = ?", chain).Order("score DESC").Limit(limit).Find(&scores).Error
+	return scores, err
+}
@@ -1,13 +1,56 @@
 package database
 
-import "log"
+import (
+	"log"
+	"strings"
+)
 
 func (d *Database) MigrateDB() error {
 	log.Println("Database Migration in Process...")
-	err := d.Client.AutoMigrate(&BuyTransaction{}, &SellTransaction{})
+
+	// Sells used to record the ETH received as a float in ETH, convert those to wei strings
+	var legacySells []string
+	for _, table := range []string{"sell_transactions", PaperSellTransaction{}.TableName()} {
+		legacy, err := d.hasFloatColumn(table, "eth_received")
+		if err != nil {
+			return err
+		}
+		if legacy {
+			legacySells = append(legacySells, table)
+		}
+	}
+
+	err := d.Client.AutoMigrate(&BuyTransaction{}, &SellTransaction{}, &Token{}, &SubWallet{}, &Transfer{}, &Leader{}, &Strategy{}, &TokenListEntry{}, &Setting{}, &PaperBuyTransaction{}, &PaperSellTransaction{}, &LeaderScore{}, &ProcessedBlock{}, &Approval{})
 	if err != nil {
 		return err
 	}
+
+	for _, table := range legacySells {
+		err := d.Client.Exec("UPDATE " + table + " SET eth_received = printf('%.0f', CAST(eth_received AS REAL) * 1e18)").Error
+		if err != nil {
+			return err
+		}
+	}
 	log.Println("Database Migration Complete!")
 	return nil
 }
+
+// hasFloatColumn reports whether a table exists with a floating point column of the given name.
+func (d *Database) hasFloatColumn(table, column string) (bool, error) {
+	if !d.Client.Migrator().HasTable(table) {
+		return false, nil
+	}
+	columns, err := d.Client.Migrator().ColumnTypes(table)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range columns {
+		if c.Name() == column {
+			switch strings.ToLower(c.DatabaseTypeName()) {
+			case "real", "float", "double", "numeric":
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -2,28 +2,106 @@ package database
 
 import (
 	"context"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type SellTransaction struct {
 	gorm.Model
-	ContractAddress string  `gorm:"type:varchar(42);unique;not null"`
-	ETHReceived     float64 `gorm:"not null"`
-	Hash            string  `gorm:"type:varchar(66);unique;not null"`
+	ContractAddress string `gorm:"type:varchar(42);index;not null"`
+	// ETHReceived is the ETH received in wei, as a decimal string.
+	ETHReceived string `gorm:"type:varchar(78)"`
+	Hash        string `gorm:"type:varchar(66);unique;not null"`
+	Chain       string `gorm:"type:varchar(32);index"`
+	Wallet      string `gorm:"type:varchar(42);index"`
+	Leader      string `gorm:"type:varchar(42);index"`
+	LeaderHash  string `gorm:"type:varchar(66)"`
+	// Reorged is set while the leader's swap or ours is missing from the chain after a reorg.
+	Reorged bool `gorm:"index"`
+}
+
+// PaperSellTransaction is a simulated sell recorded in paper trading mode.
+type PaperSellTransaction struct {
+	SellTransaction
+}
+
+func (PaperSellTransaction) TableName() string {
+	return "paper_sell_transactions"
+}
+
+// sells scopes a query to the live or paper sell table.
+func (d *Database) sells(ctx context.Context) *gorm.DB {
+	if d.Paper {
+		return d.Client.WithContext(ctx).Table(PaperSellTransaction{}.TableName())
+	}
+	return d.Client.WithContext(ctx).Model(&SellTransaction{})
 }
 
 func (d *Database) CreateSellTransaction(ctx context.Context, txn SellTransaction) error {
-	return d.Client.WithContext(ctx).Create(&txn).Error
+	return d.sells(ctx).Create(&txn).Error
 }
 
 func (d *Database) GetSellTransactionByCA(ctx context.Context, CA string) (SellTransaction, error) {
 	var txn SellTransaction
-	err := d.Client.WithContext(ctx).Where("ContractAddress = ?", CA).First(&txn).Error
+	err := d.sells(ctx).Where("contract_address = ?", CA).First(&txn).Error
 	return txn, err
 }
 
 func (d *Database) GetSellTransactionByHash(ctx context.Context, hash string) (SellTransaction, error) {
 	var txn SellTransaction
-	err := d.Client.WithContext(ctx).Where("Hash = ?", hash).First(&txn).Error
+	err := d.sells(ctx).Where("hash = ?", hash).First(&txn).Error
 	return txn, err
 }
+
+// SetSellReorged marks a sell as dropped by a reorg, or as included again.
+func (d *Database) SetSellReorged(ctx context.Context, hash string, reorged bool) error {
+	return d.sells(ctx).Where("hash = ?", hash).Update("reorged", reorged).Error
+}
+
+// SetSellReceived records the ETH a mined sell actually received, in wei.
+func (d *Database) SetSellReceived(ctx context.Context, hash, received string) error {
+	return d.sells(ctx).Where("hash = ?", hash).Update("eth_received", received).Error
+}
+
+// DeleteSellTransaction removes a sell that never landed, reopening its position.
+func (d *Database) DeleteSellTransaction(ctx context.Context, hash string) error {
+	return d.sells(ctx).Unscoped().Where("hash = ?", hash).Delete(&SellTransaction{}).Error
+}
+
+// ListReorgedSellTransactions returns the sells on a chain currently marked as reorged.
+func (d *Database) ListReorgedSellTransactions(ctx context.Context, chain string) ([]SellTransaction, error) {
+	var txns []SellTransaction
+	err := d.sells(ctx).Where("chain = ? AND reorged = ?", chain, true).Order("created_at").Find(&txns).Error
+	return txns, err
+}
+
+// GetLastSellTransaction returns the most recent sell of a token from a wallet that a reorg
+// has not dropped.
+func (d *Database) GetLastSellTransaction(ctx context.Context, wallet, CA string) (SellTransaction, error) {
+	var txn SellTransaction
+	err := d.sells(ctx).Where("wallet = ? AND contract_address = ? AND reorged = ?", wallet, CA, false).Order("created_at DESC").First(&txn).Error
+	return txn, err
+}
+
+// ListSellTransactions returns every sell of a token made from a wallet, those a reorg dropped
+// aside.
+func (d *Database) ListSellTransactions(ctx context.Context, wallet, CA string) ([]SellTransaction, error) {
+	var txns []SellTransaction
+	err := d.sells(ctx).Where("wallet = ? AND contract_address = ? AND reorged = ?", wallet, CA, false).Order("created_at").Find(&txns).Error
+	return txns, err
+}
+
+// ListSellTransactionsSince returns the sells made on a chain since the given time.
+func (d *Database) ListSellTransactionsSince(ctx context.Context, chain string, since time.Time) ([]SellTransaction, error) {
+	var txns []SellTransaction
+	err := d.sells(ctx).Where("chain = ? AND created_at >= ?", chain, since).Order("created_at").Find(&txns).Error
+	return txns, err
+}
+
+// CountSellTransactionsSince counts the sells made on a chain since the given time.
+func (d *Database) CountSellTransactionsSince(ctx context.Context, chain string, since time.Time) (int64, error) {
+	var count int64
+	err := d.sells(ctx).Where("chain = ? AND created_at >= ?", chain, since).Count(&count).Error
+	return count, err
+}
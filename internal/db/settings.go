@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting is a runtime switch shared between the server and the command line tools.
+type Setting struct {
+	gorm.Model
+	Name  string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	Value string
+}
+
+// GetSetting returns the value of a setting, or an empty string if it has never been set.
+func (d *Database) GetSetting(ctx context.Context, name string) (string, error) {
+	var setting Setting
+	err := d.Client.WithContext(ctx).Where("name = ?", name).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return setting.Value, err
+}
+
+func (d *Database) SetSetting(ctx context.Context, name, value string) error {
+	return d.Client.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&Setting{Name: name, Value: value}).Error
+}
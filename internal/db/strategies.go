@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Strategy sizing modes.
+const (
+	SizingFixed        = "fixed"
+	SizingProportional = "proportional"
+)
+
+// Strategy holds the copy rules applied to a leader's signals.
+type Strategy struct {
+	gorm.Model
+	Name string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	// SizingMode is SizingFixed (FixedETH per buy) or SizingProportional (ProportionalPct of the leader's buy).
+	SizingMode      string  `gorm:"type:varchar(16);not null"`
+	FixedETH        float64 `gorm:"not null"`
+	ProportionalPct float64 `gorm:"not null"`
+	MaxETHPerTrade  float64 `gorm:"not null"`
+	// MaxPositionETH caps the ETH spent on one token across all buys, 0 for no cap.
+	MaxPositionETH float64 `gorm:"not null"`
+	SlippagePct    float64 `gorm:"not null"`
+	// AllowedTokens is a comma separated list of token addresses; empty allows any token.
+	AllowedTokens string
+	MirrorExits   bool    `gorm:"not null"`
+	TakeProfitPct float64 `gorm:"not null"`
+	StopLossPct   float64 `gorm:"not null"`
+	DelayMs       int64   `gorm:"not null"`
+	// Broadcast is how swaps are submitted: "public", "private" or "bundle". Empty is public.
+	Broadcast string `gorm:"type:varchar(16)"`
+}
+
+func (d *Database) CreateStrategy(ctx context.Context, strategy *Strategy) error {
+	return d.Client.WithContext(ctx).Create(strategy).Error
+}
+
+func (d *Database) UpdateStrategy(ctx context.Context, strategy *Strategy) error {
+	return d.Client.WithContext(ctx).Save(strategy).Error
+}
+
+func (d *Database) GetStrategy(ctx context.Context, id uint) (Strategy, error) {
+	var strategy Strategy
+	err := d.Client.WithContext(ctx).First(&strategy, id).Error
+	return strategy, err
+}
+
+func (d *Database) GetStrategyByName(ctx context.Context, name string) (Strategy, error) {
+	var strategy Strategy
+	err := d.Client.WithContext(ctx).Where("name = ?", name).First(&strategy).Error
+	return strategy, err
+}
+
+func (d *Database) ListStrategies(ctx context.Context) ([]Strategy, error) {
+	var strategies []Strategy
+	err := d.Client.WithContext(ctx).Order("name").Find(&strategies).Error
+	return strategies, err
+}
+
+// AssignStrategy links a leader to a strategy.
+func (d *Database) AssignStrategy(ctx context.Context, chain, address string, strategyID uint) error {
+	result := d.Client.WithContext(ctx).Model(&Leader{}).Where("chain = ? AND address = ?", chain, address).Update("strategy_id", strategyID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Token list entry kinds and lists.
+const (
+	ListKindToken    = "token"
+	ListKindDeployer = "deployer"
+
+	ListAllow = "allow"
+	ListDeny  = "deny"
+)
+
+// TokenListEntry allows or denies a token, or every token from a deployer, for copy buys.
+type TokenListEntry struct {
+	gorm.Model
+	Chain   string `gorm:"type:varchar(32);uniqueIndex:idx_token_list_entry;not null"`
+	Address string `gorm:"type:varchar(42);uniqueIndex:idx_token_list_entry;not null"`
+	Kind    string `gorm:"type:varchar(16);uniqueIndex:idx_token_list_entry;not null"`
+	List    string `gorm:"type:varchar(8);index;not null"`
+	Reason  string
+}
+
+func (d *Database) SaveTokenListEntry(ctx context.Context, entry TokenListEntry) error {
+	return d.Client.WithContext(ctx).
+		Where(TokenListEntry{Chain: entry.Chain, Address: entry.Address, Kind: entry.Kind}).
+		Assign(TokenListEntry{List: entry.List, Reason: entry.Reason}).
+		FirstOrCreate(&entry).Error
+}
+
+func (d *Database) ListTokenListEntries(ctx context.Context, chain string) ([]TokenListEntry, error) {
+	var entries []TokenListEntry
+	err := d.Client.WithContext(ctx).Where("chain = ?", chain).Order("list, kind, created_at").Find(&entries).Error
+	return entries, err
+}
+
+func (d *Database) DeleteTokenListEntry(ctx context.Context, chain, address, kind string) error {
+	result := d.Client.WithContext(ctx).Unscoped().Where("chain = ? AND address = ? AND kind = ?", chain, address, kind).Delete(&TokenListEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Token struct {
+	gorm.Model
+	Chain           string `gorm:"type:varchar(32);uniqueIndex:idx_token_chain_ca;not null"`
+	ContractAddress string `gorm:"type:varchar(42);uniqueIndex:idx_token_chain_ca;not null"`
+	Name            string `gorm:"not null"`
+	Symbol          string `gorm:"type:varchar(32);not null"`
+	Decimals        uint8  `gorm:"not null"`
+	TotalSupply     string `gorm:"type:varchar(78);not null"`
+}
+
+// SaveToken inserts the token or updates its metadata if it is already known.
+func (d *Database) SaveToken(ctx context.Context, token Token) error {
+	return d.Client.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain"}, {Name: "contract_address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "symbol", "decimals", "total_supply", "updated_at"}),
+	}).Create(&token).Error
+}
+
+func (d *Database) GetTokenByCA(ctx context.Context, chain, CA string) (Token, error) {
+	var token Token
+	err := d.Client.WithContext(ctx).Where("chain = ? AND contract_address = ?", chain, CA).First(&token).Error
+	return token, err
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transfer kinds recorded by the treasury.
+const (
+	TransferKindTopUp     = "topup"
+	TransferKindSweep     = "sweep"
+	TransferKindRebalance = "rebalance"
+)
+
+// Transfer is an ETH movement between our own wallets.
+type Transfer struct {
+	gorm.Model
+	Chain       string `gorm:"type:varchar(32);not null"`
+	Kind        string `gorm:"type:varchar(16);index;not null"`
+	FromAddress string `gorm:"type:varchar(42);index;not null"`
+	ToAddress   string `gorm:"type:varchar(42);index;not null"`
+	AmountWei   string `gorm:"type:varchar(78);not null"`
+	Hash        string `gorm:"type:varchar(66);unique;not null"`
+}
+
+func (d *Database) CreateTransfer(ctx context.Context, transfer Transfer) error {
+	return d.Client.WithContext(ctx).Create(&transfer).Error
+}
+
+func (d *Database) ListTransfersByAddress(ctx context.Context, address string) ([]Transfer, error) {
+	var transfers []Transfer
+	err := d.Client.WithContext(ctx).Where("from_address = ? OR to_address = ?", address, address).Order("created_at").Find(&transfers).Error
+	return transfers, err
+}
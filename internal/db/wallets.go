@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// SubWallet is a follower wallet derived from the HD mnemonic. Only its derivation
+// index and address are stored; the key is re-derived at startup.
+type SubWallet struct {
+	gorm.Model
+	DerivationIndex uint32 `gorm:"uniqueIndex;not null"`
+	Address         string `gorm:"type:varchar(42);uniqueIndex;not null"`
+	Label           string `gorm:"type:varchar(64)"`
+}
+
+func (d *Database) CreateSubWallet(ctx context.Context, wallet SubWallet) error {
+	return d.Client.WithContext(ctx).Create(&wallet).Error
+}
+
+func (d *Database) ListSubWallets(ctx context.Context) ([]SubWallet, error) {
+	var wallets []SubWallet
+	err := d.Client.WithContext(ctx).Order("derivation_index").Find(&wallets).Error
+	return wallets, err
+}
+
+func (d *Database) GetSubWalletByIndex(ctx context.Context, index uint32) (SubWallet, error) {
+	var wallet SubWallet
+	err := d.Client.WithContext(ctx).Where("derivation_index = ?", index).First(&wallet).Error
+	return wallet, err
+}
+
+func (d *Database) GetSubWalletByAddress(ctx context.Context, address string) (SubWallet, error) {
+	var wallet SubWallet
+	err := d.Client.WithContext(ctx).Where("address = ?", address).First(&wallet).Error
+	return wallet, err
+}
+
+// NextSubWalletIndex returns the first derivation index that has not been used yet.
+func (d *Database) NextSubWalletIndex(ctx context.Context) (uint32, error) {
+	var wallet SubWallet
+	err := d.Client.WithContext(ctx).Order("derivation_index DESC").First(&wallet).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return wallet.DerivationIndex + 1, nil
+}
+
+func (d *Database) LabelSubWallet(ctx context.Context, index uint32, label string) error {
+	result := d.Client.WithContext(ctx).Model(&SubWallet{}).Where("derivation_index = ?", index).Update("label", label)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"sort"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Defaults for scans that leave the thresholds unset.
+const (
+	DefaultMinTrades     = 5
+	DefaultMinHoldBlocks = 2
+)
+
+// Scanner ranks the wallets trading a set of token/WETH pairs by how well they did over a
+// block window, to find leaders worth copying.
+type Scanner struct {
+	Chain    string
+	Client   *ethclient.Client
+	WETH     common.Address
+	LogRange uint64
+	// MinTrades is how many closed trades a wallet needs for its full score; fewer scale it down.
+	MinTrades int
+	// MinHoldBlocks scores wallets that hold for less on average at zero, which keeps
+	// sandwich and arbitrage bots off the board since their trades can't be copied.
+	MinHoldBlocks float64
+}
+
+type position struct {
+	cost      *big.Int
+	tokens    *big.Int
+	openBlock uint64
+}
+
+type wallet struct {
+	score     database.LeaderScore
+	realised  *big.Int
+	held      uint64
+	positions map[common.Address]*position
+}
+
+// Scan walks the Swap events of the pairs between fromBlock and toBlock and returns a score
+// for every wallet that traded them, best first. Only tokens bought inside the window count
+// towards realised PnL, since the cost of anything bought before it is unknown.
+func (s *Scanner) Scan(ctx context.Context, pairs []common.Address, fromBlock, toBlock uint64) ([]database.LeaderScore, error) {
+	swaps, err := evm.FindPairSwaps(ctx, s.Client, pairs, s.WETH, fromBlock, toBlock, s.LogRange)
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := make(map[common.Address]*wallet)
+	for _, swap := range swaps {
+		w := wallets[swap.Trader]
+		if w == nil {
+			w = &wallet{
+				score: database.LeaderScore{
+					Chain:     s.Chain,
+					Address:   swap.Trader.Hex(),
+					FromBlock: fromBlock,
+					ToBlock:   toBlock,
+				},
+				realised:  new(big.Int),
+				positions: make(map[common.Address]*position),
+			}
+			wallets[swap.Trader] = w
+		}
+
+		open := w.positions[swap.Token]
+		if swap.Side == evm.SideBuy {
+			w.score.Buys++
+			if open == nil {
+				open = &position{cost: new(big.Int), tokens: new(big.Int), openBlock: swap.BlockNumber}
+				w.positions[swap.Token] = open
+			}
+			open.cost.Add(open.cost, swap.ETHAmount)
+			open.tokens.Add(open.tokens, swap.TokenAmount)
+			continue
+		}
+
+		w.score.Sells++
+		if open == nil || open.tokens.Sign() == 0 {
+			continue
+		}
+
+		// Sells are charged the average cost of the tokens sold, and anything beyond what
+		// was bought in the window is ignored.
+		sold := swap.TokenAmount
+		proceeds := swap.ETHAmount
+		if sold.Cmp(open.tokens) > 0 {
+			proceeds = new(big.Int).Div(new(big.Int).Mul(proceeds, open.tokens), sold)
+			sold = open.tokens
+		}
+		cost := new(big.Int).Div(new(big.Int).Mul(open.cost, sold), open.tokens)
+		pnl := new(big.Int).Sub(proceeds, cost)
+
+		w.realised.Add(w.realised, pnl)
+		if pnl.Sign() > 0 {
+			w.score.Wins++
+		} else {
+			w.score.Losses++
+		}
+		w.held += swap.BlockNumber - open.openBlock
+
+		open.cost.Sub(open.cost, cost)
+		open.tokens.Sub(open.tokens, sold)
+		if open.tokens.Sign() == 0 {
+			delete(w.positions, swap.Token)
+		}
+	}
+
+	scores := make([]database.LeaderScore, 0, len(wallets))
+	for _, w := range wallets {
+		scores = append(scores, s.finish(w))
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].RealisedETH > scores[j].RealisedETH
+	})
+	return scores, nil
+}
+
+// finish fills in the derived fields of a wallet's score. The score is realised ETH weighted
+// by win rate and by how many closed trades back it up, so a single lucky trade ranks below
+// a steady record.
+func (s *Scanner) finish(w *wallet) database.LeaderScore {
+	score := w.score
+	score.RealisedETH, _ = new(big.Float).Quo(new(big.Float).SetInt(w.realised), big.NewFloat(1e18)).Float64()
+
+	closed := score.Wins + score.Losses
+	if closed == 0 {
+		return score
+	}
+	score.WinRate = float64(score.Wins) / float64(closed) * 100
+	score.AvgHoldBlocks = float64(w.held) / float64(closed)
+
+	minHold := s.MinHoldBlocks
+	if minHold == 0 {
+		minHold = DefaultMinHoldBlocks
+	}
+	if score.AvgHoldBlocks < minHold {
+		return score
+	}
+
+	minTrades := s.MinTrades
+	if minTrades <= 0 {
+		minTrades = DefaultMinTrades
+	}
+	confidence := math.Min(1, float64(closed)/float64(minTrades))
+	score.Score = score.RealisedETH * score.WinRate / 100 * confidence
+	return score
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"copytrader/internal/cache"
+	"copytrader/internal/evm"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Cooldown modes for repeat buys from the same leader.
+const (
+	CooldownSkip  = "skip"
+	CooldownMerge = "merge"
+)
+
+// DefaultMergeInterval is how often merged buys are checked for a closed window.
+const DefaultMergeInterval = 5 * time.Second
+
+// Cooldowns dedupes repeat signals within a window, keyed by (leader, token) and by token.
+// State lives in Redis so it is shared across processes and survives restarts. A zero window
+// disables that key.
+type Cooldowns struct {
+	Cache        *cache.Cache
+	LeaderWindow time.Duration
+	TokenWindow  time.Duration
+	// Mode decides what happens to repeat buys from the same leader: CooldownSkip drops them,
+	// CooldownMerge adds them up and copies them as one buy once the leader window closes.
+	Mode string
+}
+
+func (c *Cooldowns) leaderKey(signal *evm.Signal) string {
+	return fmt.Sprintf("cooldown:%s:%s:leader:%s:%s", signal.Chain, signal.Side, signal.Leader.Hex(), signal.Token.Hex())
+}
+
+func (c *Cooldowns) tokenKey(signal *evm.Signal) string {
+	return fmt.Sprintf("cooldown:%s:%s:token:%s", signal.Chain, signal.Side, signal.Token.Hex())
+}
+
+func (c *Cooldowns) mergeKey(signal *evm.Signal) string {
+	return fmt.Sprintf("cooldown:%s:merge:%s:%s", signal.Chain, signal.Leader.Hex(), signal.Token.Hex())
+}
+
+func (c *Cooldowns) mergesKey(chain string) string {
+	return fmt.Sprintf("cooldown:%s:merges", chain)
+}
+
+// Admit reports whether a signal should be copied now, with the reason when it is not.
+func (c *Cooldowns) Admit(ctx context.Context, signal *evm.Signal) (bool, string, error) {
+	if c.LeaderWindow > 0 {
+		key := c.leaderKey(signal)
+		first, err := c.Cache.Client.SetNX(ctx, key, signal.TxHash.Hex(), c.LeaderWindow).Result()
+		if err != nil {
+			return false, "", fmt.Errorf("failed to set leader cooldown: %v", err)
+		}
+		if !first {
+			if c.Mode == CooldownMerge && signal.Side == evm.SideBuy {
+				if err := c.park(ctx, key, signal); err != nil {
+					return false, "", err
+				}
+				return false, "merged into the buy after the leader cooldown", nil
+			}
+			return false, fmt.Sprintf("leader cooldown of %s on %s", c.LeaderWindow, signal.Token.Hex()), nil
+		}
+	}
+
+	if c.TokenWindow > 0 {
+		first, err := c.Cache.Client.SetNX(ctx, c.tokenKey(signal), signal.Leader.Hex(), c.TokenWindow).Result()
+		if err != nil {
+			return false, "", fmt.Errorf("failed to set token cooldown: %v", err)
+		}
+		if !first {
+			return false, fmt.Sprintf("token cooldown of %s on %s", c.TokenWindow, signal.Token.Hex()), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// park queues a repeat buy until the leader cooldown it fell into expires.
+func (c *Cooldowns) park(ctx context.Context, leaderKey string, signal *evm.Signal) error {
+	raw, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := c.Cache.Client.PTTL(ctx, leaderKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read leader cooldown: %v", err)
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	due := time.Now().Add(ttl)
+
+	mergeKey := c.mergeKey(signal)
+	_, err = c.Cache.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, mergeKey, raw)
+		// NX keeps the deadline of the first parked buy
+		pipe.ZAddNX(ctx, c.mergesKey(signal.Chain), &redis.Z{Score: float64(due.UnixMilli()), Member: mergeKey})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to park merged buy: %v", err)
+	}
+	return nil
+}
+
+// DueMerges returns one combined buy for every merge whose window has closed. Each merge is
+// claimed by exactly one caller, so several processes may poll the same Redis.
+func (c *Cooldowns) DueMerges(ctx context.Context, chain string) ([]*evm.Signal, error) {
+	keys, err := c.Cache.Client.ZRangeByScore(ctx, c.mergesKey(chain), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merged buys: %v", err)
+	}
+
+	var merged []*evm.Signal
+	for _, key := range keys {
+		claimed, err := c.Cache.Client.ZRem(ctx, c.mergesKey(chain), key).Result()
+		if err != nil {
+			return merged, fmt.Errorf("failed to claim merged buy: %v", err)
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		var items *redis.StringSliceCmd
+		_, err = c.Cache.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			items = pipe.LRange(ctx, key, 0, -1)
+			pipe.Del(ctx, key)
+			return nil
+		})
+		if err != nil {
+			return merged, fmt.Errorf("failed to load merged buy: %v", err)
+		}
+
+		var combined *evm.Signal
+		for _, raw := range items.Val() {
+			var signal evm.Signal
+			if err := json.Unmarshal([]byte(raw), &signal); err != nil {
+				continue
+			}
+			if combined == nil {
+				combined = &signal
+				continue
+			}
+			combined.AmountIn = addAmounts(combined.AmountIn, signal.AmountIn)
+			combined.AmountOutMin = addAmounts(combined.AmountOutMin, signal.AmountOutMin)
+			combined.TxHash = signal.TxHash
+		}
+		if combined != nil {
+			merged = append(merged, combined)
+		}
+	}
+	return merged, nil
+}
+
+func addAmounts(a, b *big.Int) *big.Int {
+	sum := new(big.Int)
+	if a != nil {
+		sum.Add(sum, a)
+	}
+	if b != nil {
+		sum.Add(sum, b)
+	}
+	return sum
+}
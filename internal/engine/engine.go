@@ -0,0 +1,510 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+)
+
+// DefaultExitInterval is how often open positions are checked against take profit and stop loss.
+const DefaultExitInterval = 30 * time.Second
+
+// DefaultMaxSignalAge is how old a leader swap can be and still be copied. Older ones, such as
+// those replayed from the blocks missed while the listener was down, have already moved the
+// price the copy would chase.
+const DefaultMaxSignalAge = time.Minute
+
+// Engine turns leader signals into copy trades according to each leader's strategy.
+type Engine struct {
+	Chain         string
+	Client        *ethclient.Client
+	Executor      Executor
+	DB            *database.Database
+	Listener      *evm.Listener
+	Evaluator     *Evaluator
+	Guard         *evm.LiquidityGuard
+	Filters       FilterPipeline
+	Risk          *RiskManager
+	Cooldowns     *Cooldowns
+	Tokens        *evm.TokenRegistry
+	UniswapRouter common.Address
+	Factory       common.Address
+	WETH          common.Address
+	// DefaultWallet copies leaders that have no follower wallet of their own.
+	DefaultWallet common.Address
+	ExitInterval  time.Duration
+	// MaxSignalAge is how long after its block a leader swap is still copied.
+	MaxSignalAge time.Duration
+	// Explorer is the block explorer copied trades are linked to in the logs, if any.
+	Explorer string
+}
+
+// Run consumes signals from the listener and checks exits until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) error {
+	interval := e.ExitInterval
+	if interval <= 0 {
+		interval = DefaultExitInterval
+	}
+	exits := time.NewTicker(interval)
+	defer exits.Stop()
+	merges := time.NewTicker(DefaultMergeInterval)
+	defer merges.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case signal := <-e.Listener.Signals:
+			if err := e.Handle(ctx, signal); err != nil {
+				log.Printf("Error copying %s of %s from %s: %v", signal.Side, signal.Token.Hex(), signal.Leader.Hex(), err)
+			}
+		case reorg := <-e.Listener.Reorgs:
+			if err := e.handleReorg(ctx, reorg); err != nil {
+				log.Printf("Error checking trades after reorg: %v", err)
+			}
+		case <-merges.C:
+			e.copyMerged(ctx)
+		case <-exits.C:
+			if err := e.liquidateIfRequested(ctx); err != nil {
+				log.Printf("Error liquidating: %v", err)
+			}
+			if err := e.CheckExits(ctx); err != nil {
+				log.Printf("Error checking exits: %v", err)
+			}
+			if err := e.verifyReorged(ctx); err != nil {
+				log.Printf("Error checking reorged trades: %v", err)
+			}
+		}
+	}
+}
+
+// Handle copies a single leader signal unless it is too old or repeats one still in its
+// cooldown.
+func (e *Engine) Handle(ctx context.Context, signal *evm.Signal) error {
+	if e.Listener != nil && e.Listener.IsReorged(signal.BlockHash) {
+		log.Printf("Skipping %s of %s from %s: block %d was reorged", signal.Side, signal.Token.Hex(), signal.Leader.Hex(), signal.BlockNumber)
+		return nil
+	}
+	maxAge := e.MaxSignalAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxSignalAge
+	}
+	if age := time.Since(signal.BlockTime); !signal.BlockTime.IsZero() && age > maxAge {
+		log.Printf("Skipping %s of %s from %s: block %d is %s old", signal.Side, signal.Token.Hex(), signal.Leader.Hex(), signal.BlockNumber, age.Round(time.Second))
+		return nil
+	}
+	if e.Cooldowns != nil {
+		admit, reason, err := e.Cooldowns.Admit(ctx, signal)
+		if err != nil {
+			// Copying a repeat costs less than missing trades while Redis is down
+			log.Printf("Error checking cooldowns: %v", err)
+		} else if !admit {
+			log.Printf("Skipping %s of %s from %s: %s", signal.Side, signal.Token.Hex(), signal.Leader.Hex(), reason)
+			return nil
+		}
+	}
+	return e.copy(ctx, signal)
+}
+
+// copyMerged copies the repeat buys merged during leader cooldowns that have now closed.
+func (e *Engine) copyMerged(ctx context.Context) {
+	if e.Cooldowns == nil || e.Cooldowns.Mode != CooldownMerge {
+		return
+	}
+	signals, err := e.Cooldowns.DueMerges(ctx, e.Chain)
+	if err != nil {
+		log.Printf("Error loading merged buys: %v", err)
+	}
+	for _, signal := range signals {
+		if err := e.copy(ctx, signal); err != nil {
+			log.Printf("Error copying merged buy of %s from %s: %v", signal.Token.Hex(), signal.Leader.Hex(), err)
+		}
+	}
+}
+
+func (e *Engine) copy(ctx context.Context, signal *evm.Signal) error {
+	leader, ok := e.Listener.Leader(signal.Leader)
+	if !ok {
+		return fmt.Errorf("leader %s is no longer tracked", signal.Leader.Hex())
+	}
+
+	if e.Risk != nil && signal.Side == evm.SideBuy {
+		halted, err := e.Risk.Halted(ctx)
+		if err != nil {
+			return err
+		}
+		if halted {
+			log.Printf("Skipping %s of %s from %s: %v", signal.Side, signal.Token.Hex(), signal.Leader.Hex(), ErrTradingHalted)
+			return nil
+		}
+	}
+
+	strategy, err := e.Evaluator.StrategyFor(ctx, leader)
+	if err != nil {
+		return err
+	}
+
+	wallet := e.walletFor(leader)
+	switch signal.Side {
+	case evm.SideBuy:
+		return e.copyBuy(ctx, leader, strategy, wallet, signal)
+	case evm.SideSell:
+		return e.copySell(ctx, leader, strategy, wallet, signal)
+	default:
+		return fmt.Errorf("unknown signal side %q", signal.Side)
+	}
+}
+
+func (e *Engine) walletFor(leader database.Leader) common.Address {
+	if common.IsHexAddress(leader.Wallet) {
+		return common.HexToAddress(leader.Wallet)
+	}
+	return e.DefaultWallet
+}
+
+func (e *Engine) copyBuy(ctx context.Context, leader database.Leader, strategy database.Strategy, wallet common.Address, signal *evm.Signal) error {
+	if err := e.Filters.Check(ctx, signal); err != nil {
+		log.Printf("Rejected buy of %s from %s: %v", signal.Token.Hex(), signal.Leader.Hex(), err)
+		return nil
+	}
+
+	position, err := positionCost(ctx, e.DB, wallet, signal.Token)
+	if err != nil {
+		return err
+	}
+
+	decision := EvaluateBuy(strategy, signal, position)
+	if !decision.Copy {
+		log.Printf("Skipping buy of %s from %s: %s", signal.Token.Hex(), signal.Leader.Hex(), decision.Reason)
+		return nil
+	}
+
+	amountIn := decision.AmountIn
+	if e.Guard != nil {
+		amountIn, err = e.Guard.Check(ctx, e.Client, e.Factory, signal.Token, e.WETH, amountIn)
+		if err != nil {
+			return err
+		}
+	}
+
+	if decision.Delay > 0 {
+		e.copyAfter(ctx, decision.Delay, signal, func(ctx context.Context) error {
+			return e.placeBuy(ctx, leader, strategy, wallet, signal, amountIn, decision.Slippage)
+		})
+		return nil
+	}
+	return e.placeBuy(ctx, leader, strategy, wallet, signal, amountIn, decision.Slippage)
+}
+
+// placeBuy buys amountIn worth of the signal's token within slippage, unless a risk limit stops it.
+func (e *Engine) placeBuy(ctx context.Context, leader database.Leader, strategy database.Strategy, wallet common.Address, signal *evm.Signal, amountIn *big.Int, slippage float64) error {
+	if e.Risk != nil {
+		if err := e.Risk.CheckBuy(ctx, signal.Token, amountIn); err != nil {
+			log.Printf("Skipping buy of %s from %s: %v", signal.Token.Hex(), signal.Leader.Hex(), err)
+			return nil
+		}
+	}
+
+	minTokens, err := evm.CalculateMinTokens(ctx, e.Client, e.UniswapRouter, signal.Token, e.WETH, amountIn, slippage)
+	if err != nil {
+		return fmt.Errorf("failed to calculate min tokens: %v", err)
+	}
+
+	fill, err := e.Executor.Buy(ctx, wallet, signal.Token, amountIn, minTokens, strategy.Broadcast)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Copied buy of %s from %s: %s ETH from %s (%s, %s)",
+		signal.Token.Hex(), signal.Leader.Hex(), evm.FormatUnits(fill.ETHAmount, 18), wallet.Hex(), e.Executor.Name(), e.txLink(fill.Hash))
+
+	buy := database.BuyTransaction{
+		ETHAmount:       fill.ETHAmount.String(),
+		ContractAddress: signal.Token.Hex(),
+		Ticker:          e.ticker(ctx, signal.Token),
+		Hash:            fill.Hash,
+		Chain:           e.Chain,
+		Wallet:          wallet.Hex(),
+		Leader:          leader.Address,
+		LeaderHash:      signal.TxHash.Hex(),
+		LeaderBlock:     signal.BlockNumber,
+		LeaderTime:      signal.BlockTime,
+		DetectedAt:      signal.DetectedAt,
+		BroadcastAt:     fill.BroadcastAt,
+		Block:           fill.Block,
+	}
+	if fill.TokenAmount != nil {
+		buy.TokenAmount = fill.TokenAmount.String()
+	}
+	if err := e.DB.CreateBuyTransaction(ctx, buy); err != nil {
+		return err
+	}
+
+	go e.measureFill(ctx, buy, signal, wallet, amountIn, fill.LastBlock)
+	return nil
+}
+
+func (e *Engine) copySell(ctx context.Context, leader database.Leader, strategy database.Strategy, wallet common.Address, signal *evm.Signal) error {
+	decision := EvaluateSell(strategy, signal)
+	if !decision.Copy {
+		log.Printf("Skipping sell of %s from %s: %s", signal.Token.Hex(), signal.Leader.Hex(), decision.Reason)
+		return nil
+	}
+
+	sell := func(ctx context.Context) error {
+		return e.sellAll(ctx, leader.Address, wallet, signal.Token, signal.TxHash.Hex(), decision.Slippage, strategy.Broadcast)
+	}
+	if decision.Delay > 0 {
+		e.copyAfter(ctx, decision.Delay, signal, sell)
+		return nil
+	}
+	return sell(ctx)
+}
+
+// copyAfter runs a delayed copy of a signal once delay has passed, off the Run loop so other
+// signals, reorgs and exits keep being handled in the meantime.
+func (e *Engine) copyAfter(ctx context.Context, delay time.Duration, signal *evm.Signal, copy func(ctx context.Context) error) {
+	time.AfterFunc(delay, func() {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := copy(ctx); err != nil {
+			log.Printf("Error copying %s of %s from %s: %v", signal.Side, signal.Token.Hex(), signal.Leader.Hex(), err)
+		}
+	})
+}
+
+// sellAll sells the wallet's whole balance of a token for ETH, within slippage percent of the
+// quote and through the named broadcast mode.
+func (e *Engine) sellAll(ctx context.Context, leader string, wallet, token common.Address, leaderHash string, slippage float64, broadcast string) error {
+	balance, err := e.Executor.TokenBalance(ctx, wallet, token)
+	if err != nil {
+		return fmt.Errorf("failed to get token balance: %v", err)
+	}
+	if balance.Sign() == 0 {
+		return nil
+	}
+
+	fill, err := e.Executor.Sell(ctx, wallet, token, balance, slippage, broadcast)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sold %s of %s from %s for ~%s ETH (%s, %s)",
+		e.formatAmount(ctx, token, balance), token.Hex(), wallet.Hex(), evm.FormatUnits(fill.ETHAmount, 18), e.Executor.Name(), e.txLink(fill.Hash))
+
+	err = e.DB.CreateSellTransaction(ctx, database.SellTransaction{
+		ContractAddress: token.Hex(),
+		ETHReceived:     fill.ETHAmount.String(),
+		Hash:            fill.Hash,
+		Chain:           e.Chain,
+		Wallet:          wallet.Hex(),
+		Leader:          leader,
+		LeaderHash:      leaderHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Paper sells are never mined, the executor already says where they would have landed
+	if fill.Block == 0 {
+		go e.settleSell(ctx, fill.Hash, fill.LastBlock)
+	}
+	return nil
+}
+
+// openBuys returns the buys of a token from a wallet since it was last sold.
+func openBuys(ctx context.Context, db *database.Database, wallet, token common.Address) ([]database.BuyTransaction, error) {
+	buys, err := db.ListBuyTransactions(ctx, wallet.Hex(), token.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load buys: %v", err)
+	}
+
+	var since time.Time
+	lastSell, err := db.GetLastSellTransaction(ctx, wallet.Hex(), token.Hex())
+	if err == nil {
+		since = lastSell.CreatedAt
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load sells: %v", err)
+	}
+
+	var open []database.BuyTransaction
+	for _, buy := range buys {
+		if buy.CreatedAt.After(since) {
+			open = append(open, buy)
+		}
+	}
+	return open, nil
+}
+
+// positionCost returns the ETH spent on a token from a wallet since it was last sold.
+func positionCost(ctx context.Context, db *database.Database, wallet, token common.Address) (*big.Int, error) {
+	buys, err := openBuys(ctx, db, wallet, token)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := new(big.Int)
+	for _, buy := range buys {
+		cost.Add(cost, buyCost(buy))
+	}
+	return cost, nil
+}
+
+// buyCost returns the ETH spent on a buy in wei, zero if the stored amount is unreadable.
+func buyCost(buy database.BuyTransaction) *big.Int {
+	cost, ok := new(big.Int).SetString(buy.ETHAmount, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return cost
+}
+
+// CheckExits sells positions whose PnL has reached the leader strategy's take profit or stop
+// loss, whether or not the kill switch is on.
+func (e *Engine) CheckExits(ctx context.Context) error {
+	positions, err := e.DB.ListBuyPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load positions: %v", err)
+	}
+
+	for _, position := range positions {
+		if position.Chain != e.Chain {
+			continue
+		}
+		leader, ok := e.Listener.Leader(common.HexToAddress(position.Leader))
+		if !ok {
+			continue
+		}
+		strategy, err := e.Evaluator.StrategyFor(ctx, leader)
+		if err != nil {
+			return err
+		}
+		if strategy.TakeProfitPct <= 0 && strategy.StopLossPct <= 0 {
+			continue
+		}
+
+		wallet := common.HexToAddress(position.Wallet)
+		token := common.HexToAddress(position.ContractAddress)
+
+		cost, err := positionCost(ctx, e.DB, wallet, token)
+		if err != nil || cost.Sign() == 0 {
+			continue
+		}
+		balance, err := e.Executor.TokenBalance(ctx, wallet, token)
+		if err != nil || balance.Sign() == 0 {
+			continue
+		}
+		value, err := evm.GetEstimatedETHForTokens(ctx, e.Client, e.UniswapRouter, token, e.WETH, balance)
+		if err != nil {
+			continue
+		}
+
+		ratio, _ := new(big.Float).Quo(new(big.Float).SetInt(value), new(big.Float).SetInt(cost)).Float64()
+		pnlPct := (ratio - 1) * 100
+
+		if exit, reason := ShouldExit(strategy, pnlPct); exit {
+			log.Printf("Exiting %s from %s: %s", token.Hex(), wallet.Hex(), reason)
+			if err := e.sellAll(ctx, position.Leader, wallet, token, "", strategy.SlippagePct, strategy.Broadcast); err != nil {
+				log.Printf("Error exiting %s: %v", token.Hex(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// liquidateIfRequested sells every open position when the kill switch asked for a liquidation.
+func (e *Engine) liquidateIfRequested(ctx context.Context) error {
+	if e.Risk == nil {
+		return nil
+	}
+	requested, err := e.Risk.LiquidationRequested(ctx)
+	if err != nil || !requested {
+		return err
+	}
+
+	positions, err := e.DB.ListBuyPositions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load positions: %v", err)
+	}
+
+	log.Printf("Kill switch liquidation: selling %d positions", len(positions))
+	failed := 0
+	for _, position := range positions {
+		if position.Chain != e.Chain {
+			continue
+		}
+		wallet := common.HexToAddress(position.Wallet)
+		token := common.HexToAddress(position.ContractAddress)
+		strategy := e.strategyFor(ctx, position.Leader)
+		if err := e.sellAll(ctx, position.Leader, wallet, token, "", strategy.SlippagePct, strategy.Broadcast); err != nil {
+			log.Printf("Error liquidating %s from %s: %v", token.Hex(), wallet.Hex(), err)
+			failed++
+		}
+	}
+
+	// Leave the request pending so failed sells are retried on the next tick
+	if failed > 0 {
+		return fmt.Errorf("%d positions could not be sold", failed)
+	}
+	return e.Risk.LiquidationDone(ctx)
+}
+
+// strategyFor returns the leader's strategy, the default strategy if the leader is no longer
+// tracked.
+func (e *Engine) strategyFor(ctx context.Context, address string) database.Strategy {
+	leader, ok := e.Listener.Leader(common.HexToAddress(address))
+	if !ok {
+		return DefaultStrategy
+	}
+	strategy, err := e.Evaluator.StrategyFor(ctx, leader)
+	if err != nil {
+		return DefaultStrategy
+	}
+	return strategy
+}
+
+// formatAmount renders a raw token amount with the token's decimals and symbol, when the
+// registry is available.
+func (e *Engine) formatAmount(ctx context.Context, token common.Address, amount *big.Int) string {
+	if e.Tokens == nil {
+		return amount.String()
+	}
+	return e.Tokens.FormatAmount(ctx, token, amount)
+}
+
+// txLink returns a link to a copied trade on the explorer, or just its hash without an
+// explorer or for paper trades, which never reach the chain.
+func (e *Engine) txLink(hash string) string {
+	if e.Explorer == "" || e.DB.Paper {
+		return hash
+	}
+	return e.Explorer + "/tx/" + hash
+}
+
+// ticker returns the token symbol for recording trades, truncated to the column width.
+func (e *Engine) ticker(ctx context.Context, token common.Address) string {
+	if e.Tokens == nil {
+		return ""
+	}
+	metadata, err := e.Tokens.Get(ctx, token)
+	if err != nil {
+		log.Printf("Error resolving token %s: %v", token.Hex(), err)
+		return ""
+	}
+	if len(metadata.Symbol) > 10 {
+		return metadata.Symbol[:10]
+	}
+	return metadata.Symbol
+}
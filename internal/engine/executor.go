@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Default gas units charged for simulated swaps. Sells include the approval.
+const (
+	DefaultPaperBuyGas  = 150000
+	DefaultPaperSellGas = 200000
+)
+
+// Fill is the result of a swap.
+type Fill struct {
+	Hash string
+	// ETHAmount is the ETH spent on a buy or received from a sell, in wei. For live sells it is
+	// the quote until the sell is mined.
+	ETHAmount *big.Int
+	// TokenAmount is the tokens received on a buy or sold on a sell, nil when unknown.
+	TokenAmount *big.Int
+	BroadcastAt time.Time
+	// Block is the block the swap was included in, zero until it is mined.
+	Block uint64
+	// LastBlock is the last block the swap can be included in, zero if it stays valid until it
+	// is mined or dropped. Bundles that miss it never land.
+	LastBlock uint64
+}
+
+// Executor places the swaps the engine decides on. broadcast names how a swap is submitted,
+// one of the evm.Broadcast modes, with an empty string for the public mempool. Sells fail
+// rather than return more than slippage percent less ETH than quoted.
+type Executor interface {
+	Name() string
+	Buy(ctx context.Context, wallet, token common.Address, amountIn, minTokens *big.Int, broadcast string) (*Fill, error)
+	Sell(ctx context.Context, wallet, token common.Address, amount *big.Int, slippage float64, broadcast string) (*Fill, error)
+	TokenBalance(ctx context.Context, wallet, token common.Address) (*big.Int, error)
+}
+
+// Approver is implemented by executors whose sells spend token allowances, so the engine can
+// approve a token as soon as a buy of it is mined instead of when it is sold.
+type Approver interface {
+	ApproveSells(ctx context.Context, wallet, token common.Address) error
+}
+
+// LiveExecutor sends real swaps through the MultiChainRouter.
+type LiveExecutor struct {
+	Chain         string
+	Client        *ethclient.Client
+	Router        *evm.MultiChainRouter
+	UniswapRouter common.Address
+	// UniversalRouter, when set, sells through the Universal Router with Permit2 permits
+	// instead of through UniswapRouter with a router allowance.
+	UniversalRouter common.Address
+	WETH            common.Address
+	Allowances      *evm.AllowanceManager
+}
+
+func (x *LiveExecutor) Name() string {
+	return "live"
+}
+
+func (x *LiveExecutor) Buy(ctx context.Context, wallet, token common.Address, amountIn, minTokens *big.Int, broadcast string) (*Fill, error) {
+	hash, err := x.Router.SwapETHForToken(ctx, x.Chain, wallet, x.UniswapRouter, x.WETH, token, amountIn, minTokens, broadcast)
+	if err != nil {
+		return nil, err
+	}
+	return &Fill{Hash: hash, ETHAmount: amountIn, BroadcastAt: time.Now(), LastBlock: x.lastBlock(ctx, broadcast)}, nil
+}
+
+func (x *LiveExecutor) Sell(ctx context.Context, wallet, token common.Address, amount *big.Int, slippage float64, broadcast string) (*Fill, error) {
+	expected, err := evm.GetEstimatedETHForTokens(ctx, x.Client, x.UniswapRouter, token, x.WETH, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote sell: %v", err)
+	}
+
+	minOut := percentOf(expected, 100-slippage)
+	var hash string
+	if x.UniversalRouter != (common.Address{}) {
+		permit, err := x.Allowances.Permit(ctx, x.Chain, wallet, token, x.UniversalRouter, amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to permit token: %v", err)
+		}
+		hash, err = x.Router.SwapTokensForETHWithPermit2(ctx, x.Chain, wallet, token, x.UniversalRouter, x.WETH, amount, minOut, permit, broadcast)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := x.Allowances.Ensure(ctx, x.Chain, wallet, token, x.UniswapRouter, amount); err != nil {
+			return nil, fmt.Errorf("failed to approve token: %v", err)
+		}
+		hash, err = x.Router.SwapTokensForETH(ctx, x.Chain, wallet, token, x.UniswapRouter, x.WETH, amount, minOut, broadcast)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Fill{Hash: hash, ETHAmount: expected, TokenAmount: amount, BroadcastAt: time.Now(), LastBlock: x.lastBlock(ctx, broadcast)}, nil
+}
+
+// lastBlock returns the last block a swap just sent through broadcast can land in. If it can't
+// be worked out the swap is treated as valid until mined, and left to the fill timeout.
+func (x *LiveExecutor) lastBlock(ctx context.Context, broadcast string) uint64 {
+	last, err := x.Router.InclusionDeadline(ctx, x.Chain, broadcast)
+	if err != nil {
+		log.Printf("Failed to get inclusion deadline: %v", err)
+		return 0
+	}
+	return last
+}
+
+// ApproveSells lets the router sell all of a token from wallet.
+func (x *LiveExecutor) ApproveSells(ctx context.Context, wallet, token common.Address) error {
+	spender, err := x.SellSpender()
+	if err != nil {
+		return err
+	}
+	return x.Allowances.ApproveMax(ctx, x.Chain, wallet, token, spender)
+}
+
+// SellSpender returns the contract sells need a token allowance for, Permit2 when they go
+// through the Universal Router.
+func (x *LiveExecutor) SellSpender() (common.Address, error) {
+	if x.UniversalRouter != (common.Address{}) {
+		return x.Allowances.Spender(x.Chain, x.UniversalRouter)
+	}
+	return x.UniswapRouter, nil
+}
+
+func (x *LiveExecutor) TokenBalance(ctx context.Context, wallet, token common.Address) (*big.Int, error) {
+	return evm.GetTokenBalance(ctx, x.Client, token, wallet)
+}
+
+// PaperExecutor simulates swaps from router quotes without sending transactions. Fills lose
+// SlippagePct to slippage and pay for gas, which is added to the ETH spent on buys and taken
+// from the ETH received on sells, and are taken to land in the next block. Balances come from
+// the paper trades recorded in DB.
+type PaperExecutor struct {
+	Client        *ethclient.Client
+	DB            *database.Database
+	UniswapRouter common.Address
+	WETH          common.Address
+	SlippagePct   float64
+	BuyGas        uint64
+	SellGas       uint64
+	// GasPrice in wei, nil uses the node's suggested gas price.
+	GasPrice *big.Int
+}
+
+func (x *PaperExecutor) Name() string {
+	return "paper"
+}
+
+func (x *PaperExecutor) Buy(ctx context.Context, wallet, token common.Address, amountIn, minTokens *big.Int, broadcast string) (*Fill, error) {
+	quote, err := evm.GetEstimatedTokensForETH(ctx, x.Client, x.UniswapRouter, token, x.WETH, amountIn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote buy: %v", err)
+	}
+
+	received := percentOf(quote, 100-x.SlippagePct)
+	if minTokens != nil && received.Cmp(minTokens) < 0 {
+		return nil, fmt.Errorf("simulated fill of %s tokens is below the minimum of %s", received, minTokens)
+	}
+
+	gas, err := x.gasCost(ctx, x.BuyGas)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := x.Client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	return &Fill{
+		Hash:        paperHash(),
+		ETHAmount:   new(big.Int).Add(amountIn, gas),
+		TokenAmount: received,
+		BroadcastAt: time.Now(),
+		Block:       head + 1,
+	}, nil
+}
+
+func (x *PaperExecutor) Sell(ctx context.Context, wallet, token common.Address, amount *big.Int, slippage float64, broadcast string) (*Fill, error) {
+	quote, err := evm.GetEstimatedETHForTokens(ctx, x.Client, x.UniswapRouter, token, x.WETH, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote sell: %v", err)
+	}
+
+	out := percentOf(quote, 100-x.SlippagePct)
+	if minOut := percentOf(quote, 100-slippage); out.Cmp(minOut) < 0 {
+		return nil, fmt.Errorf("simulated fill of %s wei is below the minimum of %s", out, minOut)
+	}
+
+	gas, err := x.gasCost(ctx, x.SellGas)
+	if err != nil {
+		return nil, err
+	}
+
+	received := new(big.Int).Sub(out, gas)
+	if received.Sign() < 0 {
+		received.SetInt64(0)
+	}
+
+	head, err := x.Client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	return &Fill{
+		Hash:        paperHash(),
+		ETHAmount:   received,
+		TokenAmount: amount,
+		BroadcastAt: time.Now(),
+		Block:       head + 1,
+	}, nil
+}
+
+// TokenBalance returns the tokens received by the paper buys since the position was last sold.
+func (x *PaperExecutor) TokenBalance(ctx context.Context, wallet, token common.Address) (*big.Int, error) {
+	buys, err := openBuys(ctx, x.DB, wallet, token)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := new(big.Int)
+	for _, buy := range buys {
+		amount, ok := new(big.Int).SetString(buy.TokenAmount, 10)
+		if ok {
+			balance.Add(balance, amount)
+		}
+	}
+	return balance, nil
+}
+
+func (x *PaperExecutor) gasCost(ctx context.Context, gas uint64) (*big.Int, error) {
+	gasPrice := x.GasPrice
+	if gasPrice == nil {
+		suggested, err := x.Client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+		}
+		gasPrice = suggested
+	}
+	return new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas)), nil
+}
+
+// paperHash returns a random transaction hash for a simulated fill.
+func paperHash() string {
+	hash := make([]byte, 32)
+	if _, err := rand.Read(hash); err != nil {
+		panic(err)
+	}
+	return hexutil.Encode(hash)
+}
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// maxSaneDecimals rejects tokens reporting more decimals than any legitimate ERC20 uses.
+const maxSaneDecimals = 36
+
+// Filter rejects tokens we must never copy into, regardless of the leader's strategy.
+type Filter interface {
+	Name() string
+	// Check returns an error describing why the signal's token is rejected, or nil to allow it.
+	Check(ctx context.Context, signal *evm.Signal) error
+}
+
+// FilterPipeline runs each filter in order and stops at the first rejection.
+type FilterPipeline []Filter
+
+func (p FilterPipeline) Check(ctx context.Context, signal *evm.Signal) error {
+	for _, filter := range p {
+		if err := filter.Check(ctx, signal); err != nil {
+			return fmt.Errorf("%s filter: %v", filter.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Creations caches contract creation lookups, which binary search the chain and are expensive.
+type Creations struct {
+	Client *ethclient.Client
+
+	mu        sync.Mutex
+	creations map[common.Address]*evm.ContractCreation
+}
+
+func (c *Creations) Get(ctx context.Context, address common.Address) (*evm.ContractCreation, error) {
+	c.mu.Lock()
+	creation, ok := c.creations[address]
+	c.mu.Unlock()
+	if ok {
+		return creation, nil
+	}
+
+	creation, err := evm.FindContractCreation(ctx, c.Client, address)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.creations == nil {
+		c.creations = make(map[common.Address]*evm.ContractCreation)
+	}
+	c.creations[address] = creation
+	c.mu.Unlock()
+	return creation, nil
+}
+
+// ListFilter applies the token and deployer allow and deny lists stored in the database.
+// With AllowlistOnly set, only allowlisted tokens or tokens from allowlisted deployers pass.
+type ListFilter struct {
+	Chain         string
+	DB            *database.Database
+	Creations     *Creations
+	AllowlistOnly bool
+}
+
+func (f *ListFilter) Name() string {
+	return "list"
+}
+
+func (f *ListFilter) Check(ctx context.Context, signal *evm.Signal) error {
+	entries, err := f.DB.ListTokenListEntries(ctx, f.Chain)
+	if err != nil {
+		return fmt.Errorf("failed to load token lists: %v", err)
+	}
+
+	var allowed, checkDeployer bool
+	for _, entry := range entries {
+		if entry.Kind == database.ListKindDeployer {
+			// Deployer entries only matter if they can change the outcome
+			if entry.List == database.ListDeny || f.AllowlistOnly {
+				checkDeployer = true
+			}
+			continue
+		}
+		if common.HexToAddress(entry.Address) != signal.Token {
+			continue
+		}
+		if entry.List == database.ListDeny {
+			return fmt.Errorf("token %s is denylisted: %s", signal.Token.Hex(), entry.Reason)
+		}
+		allowed = true
+	}
+
+	if checkDeployer && f.Creations != nil {
+		creation, err := f.Creations.Get(ctx, signal.Token)
+		if err != nil {
+			return fmt.Errorf("failed to find deployer of %s: %v", signal.Token.Hex(), err)
+		}
+		for _, entry := range entries {
+			if entry.Kind != database.ListKindDeployer || common.HexToAddress(entry.Address) != creation.Deployer {
+				continue
+			}
+			if entry.List == database.ListDeny {
+				return fmt.Errorf("deployer %s of %s is denylisted: %s", creation.Deployer.Hex(), signal.Token.Hex(), entry.Reason)
+			}
+			allowed = true
+		}
+	}
+
+	if f.AllowlistOnly && !allowed {
+		return fmt.Errorf("token %s is not allowlisted", signal.Token.Hex())
+	}
+	return nil
+}
+
+// MinAgeFilter rejects tokens whose WETH pair was created less than MinAge ago.
+type MinAgeFilter struct {
+	Client    *ethclient.Client
+	Factory   common.Address
+	WETH      common.Address
+	Creations *Creations
+	MinAge    time.Duration
+}
+
+func (f *MinAgeFilter) Name() string {
+	return "age"
+}
+
+func (f *MinAgeFilter) Check(ctx context.Context, signal *evm.Signal) error {
+	pair, err := evm.GetPairAddress(ctx, f.Client, f.Factory, signal.Token, f.WETH)
+	if err != nil {
+		return err
+	}
+
+	creation, err := f.Creations.Get(ctx, pair)
+	if err != nil {
+		return fmt.Errorf("failed to find creation of pair %s: %v", pair.Hex(), err)
+	}
+
+	if age := time.Since(creation.Time); age < f.MinAge {
+		return fmt.Errorf("pair %s created %s ago in block %d, minimum age is %s",
+			pair.Hex(), age.Round(time.Second), creation.Block, f.MinAge)
+	}
+	return nil
+}
+
+// HolderCountFilter rejects tokens with fewer than MinHolders holders. Tokens whose holder
+// count cannot be obtained from the explorer pass.
+type HolderCountFilter struct {
+	HTTPClient *http.Client
+	APIURL     string
+	MinHolders uint64
+}
+
+func (f *HolderCountFilter) Name() string {
+	return "holders"
+}
+
+func (f *HolderCountFilter) Check(ctx context.Context, signal *evm.Signal) error {
+	holders, err := evm.FetchHolderCount(ctx, f.HTTPClient, f.APIURL, signal.Token)
+	if err != nil {
+		if !errors.Is(err, evm.ErrHolderCountUnavailable) {
+			log.Printf("Error fetching holders of %s: %v", signal.Token.Hex(), err)
+		}
+		return nil
+	}
+
+	if holders < f.MinHolders {
+		return fmt.Errorf("token %s has %d holders, minimum is %d", signal.Token.Hex(), holders, f.MinHolders)
+	}
+	return nil
+}
+
+// ERC20Filter rejects tokens that do not answer the standard ERC20 metadata calls sensibly.
+type ERC20Filter struct {
+	Client *ethclient.Client
+}
+
+func (f *ERC20Filter) Name() string {
+	return "erc20"
+}
+
+func (f *ERC20Filter) Check(ctx context.Context, signal *evm.Signal) error {
+	_, symbol, err := evm.FetchTokenDetails(ctx, f.Client, signal.Token)
+	if err != nil {
+		return err
+	}
+	if symbol == "" {
+		return fmt.Errorf("token %s has an empty symbol", signal.Token.Hex())
+	}
+
+	decimals, err := evm.FetchTokenDecimals(ctx, f.Client, signal.Token)
+	if err != nil {
+		return err
+	}
+	if decimals > maxSaneDecimals {
+		return fmt.Errorf("token %s reports %d decimals", signal.Token.Hex(), decimals)
+	}
+
+	totalSupply, err := evm.FetchTokenTotalSupply(ctx, f.Client, signal.Token)
+	if err != nil {
+		return err
+	}
+	if totalSupply.Sign() <= 0 {
+		return fmt.Errorf("token %s has no supply", signal.Token.Hex())
+	}
+	return nil
+}
+
+// RiskFilter rejects tokens whose owner keeps admin powers scoring above MaxScore.
+type RiskFilter struct {
+	Client   *ethclient.Client
+	MaxScore int
+}
+
+func (f *RiskFilter) Name() string {
+	return "risk"
+}
+
+func (f *RiskFilter) Check(ctx context.Context, signal *evm.Signal) error {
+	report, err := evm.AnalyseTokenRisk(ctx, f.Client, signal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to analyse %s: %v", signal.Token.Hex(), err)
+	}
+
+	if report.Score > f.MaxScore {
+		return fmt.Errorf("token %s %s, maximum is %d", signal.Token.Hex(), report, f.MaxScore)
+	}
+	return nil
+}
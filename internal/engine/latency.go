@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Copies whose receipts take longer than fillTimeout to appear are taken as dropped.
+const (
+	fillTimeout      = 5 * time.Minute
+	fillPollInterval = time.Second
+)
+
+// measureFill waits for the leader's swap and our copy of it to be mined and records how far
+// behind the leader we landed and what we paid compared to them. Once our copy is mined the
+// token is approved for the sell. Copies that revert, are not mined within fillTimeout, or are
+// bundles not included by lastBlock are marked failed so they count towards no position.
+func (e *Engine) measureFill(ctx context.Context, buy database.BuyTransaction, signal *evm.Signal, wallet common.Address, amountIn *big.Int, lastBlock uint64) {
+	ctx, cancel := context.WithTimeout(ctx, fillTimeout)
+	defer cancel()
+
+	if lastBlock > 0 {
+		included, err := evm.WaitForInclusion(ctx, e.Client, common.HexToHash(buy.Hash), lastBlock, fillPollInterval)
+		if err != nil {
+			log.Printf("Failed to check inclusion of %s: %v", buy.Hash, err)
+		} else if !included {
+			log.Printf("Buy %s was not included by block %d", buy.Hash, lastBlock)
+			if err := e.DB.SetBuyFailed(context.WithoutCancel(ctx), buy.Hash); err != nil {
+				log.Printf("Failed to mark buy %s as failed: %v", buy.Hash, err)
+			}
+			return
+		}
+	}
+
+	leaderFill, err := evm.WaitForFill(ctx, e.Client, signal.TxHash, signal.Token, signal.Leader, fillPollInterval)
+	if err != nil {
+		log.Printf("Failed to get leader fill %s: %v", signal.TxHash.Hex(), err)
+	} else if leaderFill.Tokens.Sign() > 0 {
+		buy.LeaderPrice = unitPrice(signal.AmountIn, leaderFill.Tokens)
+	}
+
+	// Paper fills are never mined, the executor already says where they would have landed
+	if buy.Block == 0 {
+		fill, err := evm.WaitForFill(ctx, e.Client, common.HexToHash(buy.Hash), signal.Token, wallet, fillPollInterval)
+		if err != nil {
+			log.Printf("Failed to get fill %s: %v", buy.Hash, err)
+			// A copy that reverted, or was dropped and never mined in time, bought nothing
+			if errors.Is(err, evm.ErrSwapReverted) || errors.Is(err, context.DeadlineExceeded) {
+				if err := e.DB.SetBuyFailed(context.WithoutCancel(ctx), buy.Hash); err != nil {
+					log.Printf("Failed to mark buy %s as failed: %v", buy.Hash, err)
+				}
+			}
+			return
+		}
+		buy.Block = fill.Block
+		buy.BlockTime = fill.BlockTime
+		buy.TokenAmount = fill.Tokens.String()
+
+		// Approve the sell now so it doesn't wait on an approval later
+		if approver, ok := e.Executor.(Approver); ok {
+			if err := approver.ApproveSells(ctx, wallet, signal.Token); err != nil {
+				log.Printf("Failed to approve %s for sells: %v", signal.Token.Hex(), err)
+			}
+		}
+	}
+
+	if tokens, ok := new(big.Int).SetString(buy.TokenAmount, 10); ok && tokens.Sign() > 0 {
+		buy.FillPrice = unitPrice(amountIn, tokens)
+	}
+	if buy.LeaderPrice > 0 && buy.FillPrice > 0 {
+		buy.PriceDiffPct = (buy.FillPrice/buy.LeaderPrice - 1) * 100
+	}
+
+	if err := e.DB.UpdateBuyFill(ctx, buy); err != nil {
+		log.Printf("Failed to record fill %s: %v", buy.Hash, err)
+	}
+}
+
+// settleSell waits for a sell to be mined and records the ETH it actually received in place of
+// the quote. Sells that revert, are not mined within fillTimeout, or are bundles not included
+// by lastBlock are deleted, so the position stays open and is sold again.
+func (e *Engine) settleSell(ctx context.Context, hash string, lastBlock uint64) {
+	ctx, cancel := context.WithTimeout(ctx, fillTimeout)
+	defer cancel()
+
+	if lastBlock > 0 {
+		included, err := evm.WaitForInclusion(ctx, e.Client, common.HexToHash(hash), lastBlock, fillPollInterval)
+		if err != nil {
+			log.Printf("Failed to check inclusion of %s: %v", hash, err)
+			return
+		}
+		if !included {
+			log.Printf("Sell %s was not included by block %d", hash, lastBlock)
+			e.dropSell(ctx, hash)
+			return
+		}
+	}
+
+	received, err := evm.WaitForSale(ctx, e.Client, common.HexToHash(hash), e.WETH, fillPollInterval)
+	if err != nil {
+		log.Printf("Failed to get sale %s: %v", hash, err)
+		// A sell that reverted, or was dropped and never mined in time, sold nothing
+		if errors.Is(err, evm.ErrSwapReverted) || errors.Is(err, context.DeadlineExceeded) {
+			e.dropSell(ctx, hash)
+		}
+		return
+	}
+	if err := e.DB.SetSellReceived(ctx, hash, received.String()); err != nil {
+		log.Printf("Failed to record sale %s: %v", hash, err)
+	}
+}
+
+// dropSell deletes a sell that sold nothing.
+func (e *Engine) dropSell(ctx context.Context, hash string) {
+	if err := e.DB.DeleteSellTransaction(context.WithoutCancel(ctx), hash); err != nil {
+		log.Printf("Failed to delete sell %s: %v", hash, err)
+	}
+}
+
+// unitPrice returns the wei paid per token base unit.
+func unitPrice(eth, tokens *big.Int) float64 {
+	price, _ := new(big.Float).Quo(new(big.Float).SetInt(eth), new(big.Float).SetInt(tokens)).Float64()
+	return price
+}
+
+// Percentiles summarises a set of measurements.
+type Percentiles struct {
+	Count int
+	P50   float64
+	P90   float64
+	P99   float64
+	Max   float64
+}
+
+func percentiles(values []float64) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sort.Float64s(values)
+	// Nearest rank, so every reported value is one that was actually measured
+	rank := func(p float64) float64 {
+		return values[int(math.Ceil(p/100*float64(len(values))))-1]
+	}
+	return Percentiles{
+		Count: len(values),
+		P50:   rank(50),
+		P90:   rank(90),
+		P99:   rank(99),
+		Max:   values[len(values)-1],
+	}
+}
+
+// LeaderLatency is how quickly and how well the buys copied from one leader were filled.
+// Times are in seconds.
+type LeaderLatency struct {
+	// Leader is empty for the totals across the chain.
+	Leader string
+	// Detect is from the leader's block to us seeing it, Broadcast from there to sending our
+	// swap and Inclusion from the leader's block to ours.
+	Detect    Percentiles
+	Broadcast Percentiles
+	Inclusion Percentiles
+	// BlocksBehind is how many blocks after the leader's ours landed in.
+	BlocksBehind Percentiles
+	// PriceDiffPct is how much more than the leader we paid per token, in percent.
+	PriceDiffPct Percentiles
+}
+
+type latencySamples struct {
+	detect, broadcast, inclusion, blocks, price []float64
+}
+
+func (s *latencySamples) add(buy database.BuyTransaction) {
+	if buy.LeaderTime.IsZero() {
+		return
+	}
+	if !buy.DetectedAt.IsZero() {
+		s.detect = append(s.detect, buy.DetectedAt.Sub(buy.LeaderTime).Seconds())
+		if !buy.BroadcastAt.IsZero() {
+			s.broadcast = append(s.broadcast, buy.BroadcastAt.Sub(buy.DetectedAt).Seconds())
+		}
+	}
+	if !buy.BlockTime.IsZero() {
+		s.inclusion = append(s.inclusion, buy.BlockTime.Sub(buy.LeaderTime).Seconds())
+	}
+	if buy.Block >= buy.LeaderBlock && buy.LeaderBlock > 0 {
+		s.blocks = append(s.blocks, float64(buy.Block-buy.LeaderBlock))
+	}
+	if buy.LeaderPrice > 0 && buy.FillPrice > 0 {
+		s.price = append(s.price, buy.PriceDiffPct)
+	}
+}
+
+func (s *latencySamples) summary(leader string) *LeaderLatency {
+	return &LeaderLatency{
+		Leader:       leader,
+		Detect:       percentiles(s.detect),
+		Broadcast:    percentiles(s.broadcast),
+		Inclusion:    percentiles(s.inclusion),
+		BlocksBehind: percentiles(s.blocks),
+		PriceDiffPct: percentiles(s.price),
+	}
+}
+
+// LatencyReport returns copy latency percentiles per leader, sorted by leader, followed by the
+// totals for the chain, over the buys recorded in db since the given time.
+func LatencyReport(ctx context.Context, db *database.Database, chain string, since time.Time) ([]*LeaderLatency, error) {
+	buys, err := db.ListBuyTransactionsSince(ctx, chain, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load buys: %v", err)
+	}
+
+	all := new(latencySamples)
+	leaders := make(map[string]*latencySamples)
+	for _, buy := range buys {
+		if leaders[buy.Leader] == nil {
+			leaders[buy.Leader] = new(latencySamples)
+		}
+		leaders[buy.Leader].add(buy)
+		all.add(buy)
+	}
+
+	report := make([]*LeaderLatency, 0, len(leaders)+1)
+	for leader, samples := range leaders {
+		report = append(report, samples.summary(leader))
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Leader < report[j].Leader
+	})
+	return append(report, all.summary("")), nil
+}
@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// reorgWindow is how far back trades are checked after a reorg. It comfortably covers the
+// listener's maximum reorg depth on Base.
+const reorgWindow = 10 * time.Minute
+
+// reorgGiveUp is how long after it was made a reorged trade is checked for again. A swap a
+// reorg dropped is back within a few blocks or not at all, so past this it is settled from
+// our own swap alone.
+const reorgGiveUp = time.Hour
+
+// errTradePending is returned while a swap is still waiting in the mempool, which says
+// nothing about whether a reorg dropped it.
+var errTradePending = errors.New("transaction pending")
+
+// handleReorg re-verifies the trades that may have come from, or landed in, blocks a reorg
+// replaced. Trades whose leader swap or own swap is no longer on chain are marked as reorged;
+// buys not mined yet and swaps still pending are left for their fills to settle.
+func (e *Engine) handleReorg(ctx context.Context, reorg *evm.Reorg) error {
+	since := time.Now().Add(-reorgWindow)
+
+	buys, err := e.DB.ListBuyTransactionsSince(ctx, e.Chain, since)
+	if err != nil {
+		return fmt.Errorf("failed to load buys: %v", err)
+	}
+	for _, buy := range buys {
+		if buy.Reorged || buy.Block == 0 || buy.LeaderBlock < reorg.FromBlock && buy.Block < reorg.FromBlock {
+			continue
+		}
+		if err := e.verifyBuy(ctx, buy.Hash, buy.LeaderHash, buy.Block, buy.Reorged); err != nil {
+			return err
+		}
+	}
+
+	sells, err := e.DB.ListSellTransactionsSince(ctx, e.Chain, since)
+	if err != nil {
+		return fmt.Errorf("failed to load sells: %v", err)
+	}
+	for _, sell := range sells {
+		if sell.Reorged {
+			continue
+		}
+		if err := e.verifySell(ctx, sell.Hash, sell.LeaderHash, sell.Reorged); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyReorged checks the trades marked as reorged again, clearing the mark on those whose
+// swaps have since been included. Trades still missing after reorgGiveUp are settled for good.
+func (e *Engine) verifyReorged(ctx context.Context) error {
+	buys, err := e.DB.ListReorgedBuyTransactions(ctx, e.Chain)
+	if err != nil {
+		return fmt.Errorf("failed to load reorged buys: %v", err)
+	}
+	for _, buy := range buys {
+		if time.Since(buy.CreatedAt) > reorgGiveUp {
+			if err := e.settleReorgedBuy(ctx, buy.Hash); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.verifyBuy(ctx, buy.Hash, buy.LeaderHash, buy.Block, true); err != nil {
+			return err
+		}
+	}
+
+	sells, err := e.DB.ListReorgedSellTransactions(ctx, e.Chain)
+	if err != nil {
+		return fmt.Errorf("failed to load reorged sells: %v", err)
+	}
+	for _, sell := range sells {
+		if time.Since(sell.CreatedAt) > reorgGiveUp {
+			if err := e.settleReorgedSell(ctx, sell.Hash); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.verifySell(ctx, sell.Hash, sell.LeaderHash, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// settleReorgedBuy stops checking a buy whose leader swap never came back. If our swap is on
+// chain we hold the tokens whatever the leader did, otherwise the buy is marked failed.
+func (e *Engine) settleReorgedBuy(ctx context.Context, hash string) error {
+	block, included, err := e.ownSwapIncluded(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if included {
+		log.Printf("Copied buy %s stays on chain without its leader swap", hash)
+		return e.DB.SetBuyInclusion(ctx, hash, block, false)
+	}
+	log.Printf("Copied buy %s never came back after a reorg, marking it failed", hash)
+	return e.DB.SetBuyFailed(ctx, hash)
+}
+
+// settleReorgedSell stops checking a sell whose leader swap never came back. If our swap is on
+// chain the tokens are sold whatever the leader did, otherwise the sell is deleted so the
+// position is open again.
+func (e *Engine) settleReorgedSell(ctx context.Context, hash string) error {
+	_, included, err := e.ownSwapIncluded(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if included {
+		log.Printf("Copied sell %s stays on chain without its leader swap", hash)
+		return e.DB.SetSellReorged(ctx, hash, false)
+	}
+	log.Printf("Copied sell %s never came back after a reorg, deleting it", hash)
+	return e.DB.DeleteSellTransaction(ctx, hash)
+}
+
+// ownSwapIncluded reports whether our own swap is on chain, ignoring the leader's. Paper swaps
+// are never sent, and a swap still pending after reorgGiveUp is not counted.
+func (e *Engine) ownSwapIncluded(ctx context.Context, hash string) (uint64, bool, error) {
+	if e.DB.Paper {
+		return 0, false, nil
+	}
+	block, included, err := e.receiptBlock(ctx, hash)
+	if errors.Is(err, errTradePending) {
+		return 0, false, nil
+	}
+	return block, included, err
+}
+
+func (e *Engine) verifyBuy(ctx context.Context, hash, leaderHash string, block uint64, reorged bool) error {
+	included, newBlock, err := e.tradeIncluded(ctx, hash, leaderHash)
+	if errors.Is(err, errTradePending) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Paper buys keep the block the executor estimated
+	if !included || newBlock == 0 {
+		newBlock = block
+	}
+	if included == !reorged && newBlock == block {
+		return nil
+	}
+	logInclusion("buy", hash, included)
+	return e.DB.SetBuyInclusion(ctx, hash, newBlock, !included)
+}
+
+func (e *Engine) verifySell(ctx context.Context, hash, leaderHash string, reorged bool) error {
+	included, _, err := e.tradeIncluded(ctx, hash, leaderHash)
+	if errors.Is(err, errTradePending) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if included == !reorged {
+		return nil
+	}
+	logInclusion("sell", hash, included)
+	return e.DB.SetSellReorged(ctx, hash, !included)
+}
+
+func logInclusion(side, hash string, included bool) {
+	if included {
+		log.Printf("Copied %s %s is back on chain", side, hash)
+	} else {
+		log.Printf("Copied %s %s was dropped by a reorg", side, hash)
+	}
+}
+
+// tradeIncluded reports whether both the leader's swap and ours are on chain, and the block
+// ours is in. Paper trades are never sent, so only the leader's swap is checked for them.
+func (e *Engine) tradeIncluded(ctx context.Context, hash, leaderHash string) (bool, uint64, error) {
+	if leaderHash != "" {
+		if _, ok, err := e.receiptBlock(ctx, leaderHash); err != nil || !ok {
+			return false, 0, err
+		}
+	}
+	if e.DB.Paper {
+		return true, 0, nil
+	}
+	block, ok, err := e.receiptBlock(ctx, hash)
+	return ok, block, err
+}
+
+// receiptBlock returns the block a transaction was mined in, whether it was found on chain at
+// all, or errTradePending if the node still has it in its mempool.
+func (e *Engine) receiptBlock(ctx context.Context, hash string) (uint64, bool, error) {
+	receipt, err := e.Client.TransactionReceipt(ctx, common.HexToHash(hash))
+	if errors.Is(err, ethereum.NotFound) {
+		_, isPending, err := e.Client.TransactionByHash(ctx, common.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get transaction %s: %v", hash, err)
+		}
+		if isPending {
+			return 0, false, errTradePending
+		}
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get receipt for %s: %v", hash, err)
+	}
+	return receipt.BlockNumber.Uint64(), true, nil
+}
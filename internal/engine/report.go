@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LeaderPnL is the copy trading result for one leader. Amounts are in wei.
+type LeaderPnL struct {
+	Leader   string
+	Buys     int
+	Sells    int
+	Realised *big.Int
+	// OpenCost is the ETH spent on positions not yet sold, OpenValue what they would sell for now.
+	OpenCost  *big.Int
+	OpenValue *big.Int
+}
+
+// Unrealised returns the gain or loss on open positions.
+func (p *LeaderPnL) Unrealised() *big.Int {
+	return new(big.Int).Sub(p.OpenValue, p.OpenCost)
+}
+
+// PnLReport computes realised and unrealised PnL per leader from the trades recorded in e.DB,
+// so the same report covers live and paper trading. Open positions are valued with router
+// quotes for the balances the executor reports. A position bought for several leaders from
+// one wallet is credited to the leader of its latest buy.
+func (e *Engine) PnLReport(ctx context.Context) ([]*LeaderPnL, error) {
+	results := make(map[string]*LeaderPnL)
+	leaderPnL := func(leader string) *LeaderPnL {
+		if results[leader] == nil {
+			results[leader] = &LeaderPnL{
+				Leader:    leader,
+				Realised:  new(big.Int),
+				OpenCost:  new(big.Int),
+				OpenValue: new(big.Int),
+			}
+		}
+		return results[leader]
+	}
+
+	buys, err := e.DB.ListBuyTransactionsSince(ctx, e.Chain, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load buys: %v", err)
+	}
+	for _, buy := range buys {
+		if buy.Reorged {
+			continue
+		}
+		leaderPnL(buy.Leader).Buys++
+	}
+
+	sells, err := e.DB.ListSellTransactionsSince(ctx, e.Chain, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sells: %v", err)
+	}
+	for _, sell := range sells {
+		if sell.Reorged {
+			continue
+		}
+		realised, err := sellPnL(ctx, e.DB, sell)
+		if err != nil {
+			return nil, err
+		}
+		result := leaderPnL(sell.Leader)
+		result.Sells++
+		result.Realised.Add(result.Realised, realised)
+	}
+
+	positions, err := e.DB.ListBuyPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load positions: %v", err)
+	}
+	seen := make(map[[2]common.Address]bool)
+	for _, position := range positions {
+		if position.Chain != e.Chain {
+			continue
+		}
+		wallet := common.HexToAddress(position.Wallet)
+		token := common.HexToAddress(position.ContractAddress)
+		key := [2]common.Address{wallet, token}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		open, err := openBuys(ctx, e.DB, wallet, token)
+		if err != nil {
+			return nil, err
+		}
+		if len(open) == 0 {
+			continue
+		}
+		result := leaderPnL(open[len(open)-1].Leader)
+		for _, buy := range open {
+			result.OpenCost.Add(result.OpenCost, buyCost(buy))
+		}
+
+		balance, err := e.Executor.TokenBalance(ctx, wallet, token)
+		if err != nil || balance.Sign() == 0 {
+			continue
+		}
+		// A position that can no longer be quoted, such as after a rug, is worth nothing
+		value, err := evm.GetEstimatedETHForTokens(ctx, e.Client, e.UniswapRouter, token, e.WETH, balance)
+		if err == nil {
+			result.OpenValue.Add(result.OpenValue, value)
+		}
+	}
+
+	report := make([]*LeaderPnL, 0, len(results))
+	for _, result := range results {
+		report = append(report, result)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Leader < report[j].Leader
+	})
+	return report, nil
+}
@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Settings shared with the killswitch command.
+const (
+	SettingKillSwitch = "kill_switch"
+	SettingLiquidate  = "kill_switch_liquidate"
+)
+
+// ErrTradingHalted is returned for buys while the kill switch is on. Sells, take profit and stop
+// loss exits carry on, since they only reduce what is at risk.
+var ErrTradingHalted = errors.New("trading halted by kill switch")
+
+// RiskLimits caps what the engine may put at risk. Zero disables a limit.
+type RiskLimits struct {
+	MaxOpenPositions    int
+	MaxTokenExposureETH float64
+	MaxTotalExposureETH float64
+	MaxTradesPerHour    int
+	// DailyLossLimitETH halts new buys once today's realised loss (UTC) reaches it.
+	DailyLossLimitETH float64
+}
+
+// RiskManager enforces portfolio wide limits across every leader and wallet on a chain.
+type RiskManager struct {
+	Chain  string
+	DB     *database.Database
+	Limits RiskLimits
+}
+
+// Exposure is the ETH still at cost in open positions.
+type Exposure struct {
+	OpenPositions int
+	Tokens        map[common.Address]*big.Int
+	Total         *big.Int
+}
+
+// Exposure sums the cost of every position bought since it was last sold.
+func (r *RiskManager) Exposure(ctx context.Context) (*Exposure, error) {
+	positions, err := r.DB.ListBuyPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load positions: %v", err)
+	}
+
+	exposure := &Exposure{
+		Tokens: make(map[common.Address]*big.Int),
+		Total:  new(big.Int),
+	}
+	seen := make(map[[2]common.Address]bool)
+	for _, position := range positions {
+		if position.Chain != r.Chain {
+			continue
+		}
+		wallet := common.HexToAddress(position.Wallet)
+		token := common.HexToAddress(position.ContractAddress)
+		// Positions are held per wallet, whichever leader they were copied from
+		key := [2]common.Address{wallet, token}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		cost, err := positionCost(ctx, r.DB, wallet, token)
+		if err != nil {
+			return nil, err
+		}
+		if cost.Sign() == 0 {
+			continue
+		}
+
+		exposure.OpenPositions++
+		if exposure.Tokens[token] == nil {
+			exposure.Tokens[token] = new(big.Int)
+		}
+		exposure.Tokens[token].Add(exposure.Tokens[token], cost)
+		exposure.Total.Add(exposure.Total, cost)
+	}
+	return exposure, nil
+}
+
+// TradesSince counts the buys and sells made since the given time.
+func (r *RiskManager) TradesSince(ctx context.Context, since time.Time) (int64, error) {
+	buys, err := r.DB.CountBuyTransactionsSince(ctx, r.Chain, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count buys: %v", err)
+	}
+	sells, err := r.DB.CountSellTransactionsSince(ctx, r.Chain, since)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sells: %v", err)
+	}
+	return buys + sells, nil
+}
+
+// RealisedPnL returns the ETH gained or lost, in wei, by the sells made since the given time.
+func (r *RiskManager) RealisedPnL(ctx context.Context, since time.Time) (*big.Int, error) {
+	sells, err := r.DB.ListSellTransactionsSince(ctx, r.Chain, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sells: %v", err)
+	}
+
+	pnl := new(big.Int)
+	for _, sell := range sells {
+		if sell.Reorged {
+			continue
+		}
+		realised, err := sellPnL(ctx, r.DB, sell)
+		if err != nil {
+			return nil, err
+		}
+		pnl.Add(pnl, realised)
+	}
+	return pnl, nil
+}
+
+// sellPnL returns the ETH a sell gained or lost, in wei. The sell is charged the cost of the
+// buys made since the previous sell of the same position.
+func sellPnL(ctx context.Context, db *database.Database, sell database.SellTransaction) (*big.Int, error) {
+	buys, err := db.ListBuyTransactions(ctx, sell.Wallet, sell.ContractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load buys: %v", err)
+	}
+	history, err := db.ListSellTransactions(ctx, sell.Wallet, sell.ContractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sells: %v", err)
+	}
+
+	var previous time.Time
+	for _, earlier := range history {
+		if earlier.CreatedAt.Before(sell.CreatedAt) {
+			previous = earlier.CreatedAt
+		}
+	}
+
+	cost := new(big.Int)
+	for _, buy := range buys {
+		if buy.CreatedAt.After(previous) && !buy.CreatedAt.After(sell.CreatedAt) {
+			cost.Add(cost, buyCost(buy))
+		}
+	}
+
+	return new(big.Int).Sub(sellProceeds(sell), cost), nil
+}
+
+// sellProceeds returns the ETH received from a sell in wei, zero if the stored amount is
+// unreadable.
+func sellProceeds(sell database.SellTransaction) *big.Int {
+	proceeds, ok := new(big.Int).SetString(sell.ETHReceived, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return proceeds
+}
+
+// Halted reports whether the kill switch is on.
+func (r *RiskManager) Halted(ctx context.Context) (bool, error) {
+	value, err := r.DB.GetSetting(ctx, SettingKillSwitch)
+	if err != nil {
+		return false, fmt.Errorf("failed to read kill switch: %v", err)
+	}
+	return value == "on", nil
+}
+
+// SetKillSwitch turns the kill switch on or off. Turning it on with liquidate asks the running
+// engine to sell every open position.
+func (r *RiskManager) SetKillSwitch(ctx context.Context, on, liquidate bool) error {
+	value := "off"
+	if on {
+		value = "on"
+	}
+	if err := r.DB.SetSetting(ctx, SettingKillSwitch, value); err != nil {
+		return err
+	}
+	if on && liquidate {
+		return r.DB.SetSetting(ctx, SettingLiquidate, "pending")
+	}
+	return r.DB.SetSetting(ctx, SettingLiquidate, "")
+}
+
+// LiquidationRequested reports whether a liquidation is waiting to be carried out.
+func (r *RiskManager) LiquidationRequested(ctx context.Context) (bool, error) {
+	value, err := r.DB.GetSetting(ctx, SettingLiquidate)
+	if err != nil {
+		return false, fmt.Errorf("failed to read liquidation request: %v", err)
+	}
+	return value == "pending", nil
+}
+
+// LiquidationDone clears a liquidation request once every position has been sold.
+func (r *RiskManager) LiquidationDone(ctx context.Context) error {
+	return r.DB.SetSetting(ctx, SettingLiquidate, "")
+}
+
+// CheckBuy returns an error if buying amountIn of a token would break a limit.
+func (r *RiskManager) CheckBuy(ctx context.Context, token common.Address, amountIn *big.Int) error {
+	halted, err := r.Halted(ctx)
+	if err != nil {
+		return err
+	}
+	if halted {
+		return ErrTradingHalted
+	}
+
+	if r.Limits.MaxTradesPerHour > 0 {
+		trades, err := r.TradesSince(ctx, time.Now().Add(-time.Hour))
+		if err != nil {
+			return err
+		}
+		if trades >= int64(r.Limits.MaxTradesPerHour) {
+			return fmt.Errorf("%d trades in the last hour, limit is %d", trades, r.Limits.MaxTradesPerHour)
+		}
+	}
+
+	if r.Limits.DailyLossLimitETH > 0 {
+		pnl, err := r.RealisedPnL(ctx, time.Now().UTC().Truncate(24*time.Hour))
+		if err != nil {
+			return err
+		}
+		if loss := new(big.Int).Neg(pnl); loss.Cmp(ethToWei(r.Limits.DailyLossLimitETH)) >= 0 {
+			return fmt.Errorf("realised loss today is %s ETH, limit is %g ETH", evm.FormatUnits(loss, 18), r.Limits.DailyLossLimitETH)
+		}
+	}
+
+	if r.Limits.MaxOpenPositions <= 0 && r.Limits.MaxTokenExposureETH <= 0 && r.Limits.MaxTotalExposureETH <= 0 {
+		return nil
+	}
+	exposure, err := r.Exposure(ctx)
+	if err != nil {
+		return err
+	}
+
+	held := exposure.Tokens[token]
+	if held == nil {
+		held = new(big.Int)
+		if r.Limits.MaxOpenPositions > 0 && exposure.OpenPositions >= r.Limits.MaxOpenPositions {
+			return fmt.Errorf("%d positions open, limit is %d", exposure.OpenPositions, r.Limits.MaxOpenPositions)
+		}
+	}
+
+	if limit := ethToWei(r.Limits.MaxTokenExposureETH); limit.Sign() > 0 {
+		if after := new(big.Int).Add(held, amountIn); after.Cmp(limit) > 0 {
+			return fmt.Errorf("exposure to %s would be %s ETH, limit is %g ETH",
+				token.Hex(), evm.FormatUnits(after, 18), r.Limits.MaxTokenExposureETH)
+		}
+	}
+
+	if limit := ethToWei(r.Limits.MaxTotalExposureETH); limit.Sign() > 0 {
+		if after := new(big.Int).Add(exposure.Total, amountIn); after.Cmp(limit) > 0 {
+			return fmt.Errorf("total exposure would be %s ETH, limit is %g ETH",
+				evm.FormatUnits(after, 18), r.Limits.MaxTotalExposureETH)
+		}
+	}
+
+	return nil
+}
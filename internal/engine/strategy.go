@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultStrategy applies to leaders without a strategy of their own.
+var DefaultStrategy = database.Strategy{
+	Name:           "default",
+	SizingMode:     database.SizingFixed,
+	FixedETH:       0.01,
+	MaxETHPerTrade: 0.05,
+	SlippagePct:    5,
+	MirrorExits:    true,
+}
+
+// Decision is the outcome of evaluating a signal against a strategy.
+type Decision struct {
+	Copy     bool
+	Reason   string
+	AmountIn *big.Int
+	Slippage float64
+	Delay    time.Duration
+}
+
+func skip(format string, args ...interface{}) Decision {
+	return Decision{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Evaluator resolves each leader's strategy and turns their signals into copy decisions.
+type Evaluator struct {
+	DB      *database.Database
+	Default database.Strategy
+}
+
+// StrategyFor returns the leader's strategy, or the default strategy if none is assigned.
+func (e *Evaluator) StrategyFor(ctx context.Context, leader database.Leader) (database.Strategy, error) {
+	if leader.StrategyID == nil {
+		return e.Default, nil
+	}
+	strategy, err := e.DB.GetStrategy(ctx, *leader.StrategyID)
+	if err != nil {
+		return database.Strategy{}, fmt.Errorf("failed to load strategy %d: %v", *leader.StrategyID, err)
+	}
+	return strategy, nil
+}
+
+// EvaluateBuy sizes our copy of a leader buy. positionWei is the ETH already spent on the token.
+func EvaluateBuy(strategy database.Strategy, signal *evm.Signal, positionWei *big.Int) Decision {
+	if !tokenAllowed(strategy, signal.Token) {
+		return skip("token %s is not in strategy %s allowlist", signal.Token.Hex(), strategy.Name)
+	}
+
+	var amount *big.Int
+	switch strategy.SizingMode {
+	case database.SizingFixed:
+		amount = ethToWei(strategy.FixedETH)
+	case database.SizingProportional:
+		amount = percentOf(signal.AmountIn, strategy.ProportionalPct)
+	default:
+		return skip("unknown sizing mode %q in strategy %s", strategy.SizingMode, strategy.Name)
+	}
+
+	if maxTrade := ethToWei(strategy.MaxETHPerTrade); maxTrade.Sign() > 0 && amount.Cmp(maxTrade) > 0 {
+		amount = maxTrade
+	}
+
+	if maxPosition := ethToWei(strategy.MaxPositionETH); maxPosition.Sign() > 0 {
+		room := new(big.Int).Sub(maxPosition, positionWei)
+		if room.Sign() <= 0 {
+			return skip("position in %s already at strategy %s maximum", signal.Token.Hex(), strategy.Name)
+		}
+		if amount.Cmp(room) > 0 {
+			amount = room
+		}
+	}
+
+	if amount.Sign() <= 0 {
+		return skip("strategy %s sized the buy to zero", strategy.Name)
+	}
+
+	return Decision{
+		Copy:     true,
+		AmountIn: amount,
+		Slippage: strategy.SlippagePct,
+		Delay:    time.Duration(strategy.DelayMs) * time.Millisecond,
+	}
+}
+
+// EvaluateSell decides whether to mirror a leader's exit.
+func EvaluateSell(strategy database.Strategy, signal *evm.Signal) Decision {
+	if !strategy.MirrorExits {
+		return skip("strategy %s does not mirror exits", strategy.Name)
+	}
+	return Decision{
+		Copy:     true,
+		Slippage: strategy.SlippagePct,
+		Delay:    time.Duration(strategy.DelayMs) * time.Millisecond,
+	}
+}
+
+// ShouldExit reports whether a position's PnL in percent has hit the strategy's take profit or stop loss.
+func ShouldExit(strategy database.Strategy, pnlPct float64) (bool, string) {
+	if strategy.TakeProfitPct > 0 && pnlPct >= strategy.TakeProfitPct {
+		return true, fmt.Sprintf("take profit at %.2f%%", pnlPct)
+	}
+	if strategy.StopLossPct > 0 && pnlPct <= -strategy.StopLossPct {
+		return true, fmt.Sprintf("stop loss at %.2f%%", pnlPct)
+	}
+	return false, ""
+}
+
+func tokenAllowed(strategy database.Strategy, token common.Address) bool {
+	if strings.TrimSpace(strategy.AllowedTokens) == "" {
+		return true
+	}
+	for _, allowed := range strings.Split(strategy.AllowedTokens, ",") {
+		if common.HexToAddress(strings.TrimSpace(allowed)) == token {
+			return true
+		}
+	}
+	return false
+}
+
+// ethToWei converts a configured ETH amount to wei without float rounding.
+func ethToWei(eth float64) *big.Int {
+	if eth <= 0 {
+		return new(big.Int)
+	}
+	wei, err := evm.ParseUnits(strconv.FormatFloat(eth, 'f', -1, 64), 18)
+	if err != nil {
+		return new(big.Int)
+	}
+	return wei
+}
+
+// percentOf returns pct percent of amount, rounded down.
+func percentOf(amount *big.Int, pct float64) *big.Int {
+	if amount == nil || pct <= 0 {
+		return new(big.Int)
+	}
+	scaled := new(big.Float).Mul(new(big.Float).SetInt(amount), big.NewFloat(pct/100))
+	result, _ := scaled.Int(nil)
+	return result
+}
@@ -0,0 +1,496 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	database "copytrader/internal/db"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Permit2Address is where Uniswap's Permit2 contract is deployed on every chain.
+var Permit2Address = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+// MaxAllowance is approved once per token and spender so no later sell needs an approval.
+// Allowances above unlimitedAllowance count as unlimited, as tokens that spend from max
+// allowances still leave them that high.
+var (
+	MaxAllowance       = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	MaxPermit2Amount   = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+	unlimitedAllowance = new(big.Int).Rsh(MaxAllowance, 1)
+)
+
+// DefaultPermitExpiration is how long Permit2 allowances granted by our signatures last, and
+// permitSignatureTTL how long a router has to use the signature.
+const (
+	DefaultPermitExpiration = 30 * 24 * time.Hour
+	permitSignatureTTL      = 30 * time.Minute
+)
+
+// approvalPollInterval is how often the receipt of an approval being waited on is checked.
+const approvalPollInterval = time.Second
+
+const erc20AllowanceABI = `[{"constant":true,"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":false,"inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"name":"approve","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"nonpayable","type":"function"}]`
+
+const permit2AllowanceABI = `[{"inputs":[{"name":"owner","type":"address"},{"name":"token","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}],"stateMutability":"view","type":"function"}]`
+
+// GetAllowance returns how much of owner's tokens spender may spend.
+func GetAllowance(ctx context.Context, client *ethclient.Client, token, owner, spender common.Address) (*big.Int, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(erc20AllowanceABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC20 ABI: %v", err)
+	}
+	values, err := callView(ctx, client, parsedABI, token, nil, "allowance", owner, spender)
+	if err != nil {
+		return nil, err
+	}
+	return values[0].(*big.Int), nil
+}
+
+// Permit2Allowance is what a spender may move through Permit2 for an owner, and the nonce the
+// owner's next permit for the pair must use.
+type Permit2Allowance struct {
+	Amount     *big.Int
+	Expiration uint64
+	Nonce      uint64
+}
+
+// GetPermit2Allowance reads a spender's Permit2 allowance over owner's tokens.
+func GetPermit2Allowance(ctx context.Context, client *ethclient.Client, permit2, token, owner, spender common.Address) (*Permit2Allowance, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(permit2AllowanceABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Permit2 ABI: %v", err)
+	}
+	values, err := callView(ctx, client, parsedABI, permit2, nil, "allowance", owner, token, spender)
+	if err != nil {
+		return nil, err
+	}
+	return &Permit2Allowance{
+		Amount:     values[0].(*big.Int),
+		Expiration: values[1].(*big.Int).Uint64(),
+		Nonce:      values[2].(*big.Int).Uint64(),
+	}, nil
+}
+
+// PermitSingle is a signed Permit2 permit, as routers that accept Permit2 take it with its
+// signature in their calldata.
+type PermitSingle struct {
+	Token       common.Address
+	Amount      *big.Int
+	Expiration  uint64
+	Nonce       uint64
+	Spender     common.Address
+	SigDeadline *big.Int
+	Signature   []byte
+}
+
+// typedData returns the permit as the EIP-712 PermitSingle message Permit2 verifies.
+func (p *PermitSingle) typedData(chainID *big.Int, permit2 common.Address) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"PermitDetails": {
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint160"},
+				{Name: "expiration", Type: "uint48"},
+				{Name: "nonce", Type: "uint48"},
+			},
+			"PermitSingle": {
+				{Name: "details", Type: "PermitDetails"},
+				{Name: "spender", Type: "address"},
+				{Name: "sigDeadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "PermitSingle",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Permit2",
+			ChainId:           (*math.HexOrDecimal256)(chainID),
+			VerifyingContract: permit2.Hex(),
+		},
+		// Numbers as decimal strings, so they survive the JSON sent to remote signers
+		Message: apitypes.TypedDataMessage{
+			"details": map[string]interface{}{
+				"token":      p.Token.Hex(),
+				"amount":     p.Amount.String(),
+				"expiration": fmt.Sprint(p.Expiration),
+				"nonce":      fmt.Sprint(p.Nonce),
+			},
+			"spender":     p.Spender.Hex(),
+			"sigDeadline": p.SigDeadline.String(),
+		},
+	}
+}
+
+// abiEncode returns the permit and its signature ABI encoded, as the Universal Router's
+// PERMIT2_PERMIT command takes them.
+func (p *PermitSingle) abiEncode() ([]byte, error) {
+	permitType, err := abi.NewType("tuple", "", []abi.ArgumentMarshaling{
+		{Name: "details", Type: "tuple", Components: []abi.ArgumentMarshaling{
+			{Name: "token", Type: "address"},
+			{Name: "amount", Type: "uint160"},
+			{Name: "expiration", Type: "uint48"},
+			{Name: "nonce", Type: "uint48"},
+		}},
+		{Name: "spender", Type: "address"},
+		{Name: "sigDeadline", Type: "uint256"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	bytesType, _ := abi.NewType("bytes", "", nil)
+
+	type permitDetails struct {
+		Token      common.Address
+		Amount     *big.Int
+		Expiration *big.Int
+		Nonce      *big.Int
+	}
+	type permitSingle struct {
+		Details     permitDetails
+		Spender     common.Address
+		SigDeadline *big.Int
+	}
+	encoded, err := abi.Arguments{{Type: permitType}, {Type: bytesType}}.Pack(permitSingle{
+		Details: permitDetails{
+			Token:      p.Token,
+			Amount:     p.Amount,
+			Expiration: new(big.Int).SetUint64(p.Expiration),
+			Nonce:      new(big.Int).SetUint64(p.Nonce),
+		},
+		Spender:     p.Spender,
+		SigDeadline: p.SigDeadline,
+	}, p.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack permit: %v", err)
+	}
+	return encoded, nil
+}
+
+type allowanceKey struct {
+	chain   string
+	owner   common.Address
+	token   common.Address
+	spender common.Address
+}
+
+// AllowanceManager grants the token allowances sells need. It reads allowance() before
+// approving, approves the maximum once per token and spender, and remembers what it knows so
+// sells don't wait on an approval, or even a read, every time. For routers that accept
+// Permit2 the token is approved to Permit2 instead and swaps carry a signed permit.
+// Approvals are recorded in DB so they can be revoked.
+type AllowanceManager struct {
+	Router           *MultiChainRouter
+	DB               *database.Database
+	PermitExpiration time.Duration
+
+	mu sync.Mutex
+	// allowances are what spenders may still spend, as last read or approved.
+	allowances map[allowanceKey]*big.Int
+	// pending are approvals sent but not known to be mined.
+	pending map[allowanceKey]common.Hash
+	// locks are held from checking an allowance until its approval is settled, so concurrent
+	// callers, such as a sell and the approval after a buy, send one approval between them.
+	locks map[allowanceKey]*sync.Mutex
+}
+
+func NewAllowanceManager(router *MultiChainRouter, db *database.Database) *AllowanceManager {
+	return &AllowanceManager{
+		Router:           router,
+		DB:               db,
+		PermitExpiration: DefaultPermitExpiration,
+		allowances:       make(map[allowanceKey]*big.Int),
+		pending:          make(map[allowanceKey]common.Hash),
+		locks:            make(map[allowanceKey]*sync.Mutex),
+	}
+}
+
+// lock takes the lock of an allowance and returns the function releasing it.
+func (a *AllowanceManager) lock(key allowanceKey) func() {
+	a.mu.Lock()
+	l, ok := a.locks[key]
+	if !ok {
+		l = new(sync.Mutex)
+		a.locks[key] = l
+	}
+	a.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock
+}
+
+// Ensure makes sure spender may spend amount of owner's tokens, approving the maximum if not.
+// It returns once the approval is mined, so the swap sent next can be gas estimated and can't
+// reach a block, through a private route or another pooled node, ahead of it.
+func (a *AllowanceManager) Ensure(ctx context.Context, chain string, owner, token, spender common.Address, amount *big.Int) error {
+	return a.ensure(ctx, chain, owner, token, spender, amount, false)
+}
+
+// ApproveMax approves the maximum for spender unless its allowance is already unlimited, so
+// that a later sell needs no approval, and waits for the approval to be mined. Call it ahead of
+// time, such as when a buy is mined.
+func (a *AllowanceManager) ApproveMax(ctx context.Context, chain string, owner, token, spender common.Address) error {
+	return a.ensure(ctx, chain, owner, token, spender, unlimitedAllowance, true)
+}
+
+// ensure approves the maximum unless spender may spend amount, waiting for an approval already
+// pending for it first. Unless fresh, a cached allowance is trusted.
+func (a *AllowanceManager) ensure(ctx context.Context, chain string, owner, token, spender common.Address, amount *big.Int, fresh bool) error {
+	client, config, err := a.Router.chain(chain)
+	if err != nil {
+		return err
+	}
+	key := allowanceKey{config.Name, owner, token, spender}
+	defer a.lock(key)()
+
+	// A failed earlier approval is simply made again
+	if _, err := a.settle(ctx, client, key); err != nil {
+		return err
+	}
+
+	if !fresh && a.spend(key, amount) {
+		return nil
+	}
+
+	allowance, err := GetAllowance(ctx, client, token, owner, spender)
+	if err != nil {
+		return fmt.Errorf("failed to read allowance: %v", err)
+	}
+	a.remember(key, allowance)
+	if allowance.Cmp(amount) >= 0 {
+		if !fresh {
+			a.spend(key, amount)
+		}
+		return nil
+	}
+
+	hash, err := a.Router.SendApproval(ctx, config.Name, owner, token, spender, MaxAllowance)
+	if err != nil {
+		return fmt.Errorf("failed to approve %s for %s: %v", token.Hex(), spender.Hex(), err)
+	}
+
+	a.mu.Lock()
+	a.pending[key] = common.HexToHash(hash)
+	a.allowances[key] = new(big.Int).Set(MaxAllowance)
+	a.mu.Unlock()
+
+	err = a.DB.SaveApproval(ctx, database.Approval{Chain: config.Name, Owner: owner.Hex(), Token: token.Hex(), Spender: spender.Hex(), TxHash: hash})
+	if err != nil {
+		log.Printf("Failed to record approval %s: %v", hash, err)
+	}
+
+	approved, err := a.settle(ctx, client, key)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return fmt.Errorf("approval %s of %s for %s failed", hash, token.Hex(), spender.Hex())
+	}
+	return nil
+}
+
+// settle waits for an approval sent for key to be mined, reporting false if it failed. A
+// failed approval is forgotten so the next call approves again. Without a pending approval it
+// returns true at once.
+func (a *AllowanceManager) settle(ctx context.Context, client *ethclient.Client, key allowanceKey) (bool, error) {
+	a.mu.Lock()
+	hash, ok := a.pending[key]
+	a.mu.Unlock()
+	if !ok {
+		return true, nil
+	}
+
+	var receipt *types.Receipt
+	for {
+		var err error
+		receipt, err = client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return false, fmt.Errorf("failed to get approval receipt %s: %v", hash.Hex(), err)
+		}
+		select {
+		case <-time.After(approvalPollInterval):
+		case <-ctx.Done():
+			return false, fmt.Errorf("approval %s not mined: %v", hash.Hex(), ctx.Err())
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.pending, key)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		log.Printf("Approval %s of %s for %s failed", hash.Hex(), key.token.Hex(), key.spender.Hex())
+		delete(a.allowances, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+// spend takes amount off a cached allowance, reporting false if none covers it.
+func (a *AllowanceManager) spend(key allowanceKey, amount *big.Int) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	allowance, ok := a.allowances[key]
+	if !ok || allowance.Cmp(amount) < 0 {
+		return false
+	}
+	a.allowances[key] = new(big.Int).Sub(allowance, amount)
+	return true
+}
+
+func (a *AllowanceManager) remember(key allowanceKey, allowance *big.Int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.allowances[key] = new(big.Int).Set(allowance)
+}
+
+// Spender returns the contract that spends owner's tokens when router sells them, Permit2 for
+// routers that accept Permit2 and router itself otherwise. Approvals made ahead of a sell go
+// to it.
+func (a *AllowanceManager) Spender(chain string, router common.Address) (common.Address, error) {
+	config, err := a.Router.Chain(chain)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if config.AcceptsPermit2(router) {
+		return config.Permit2Address(), nil
+	}
+	return router, nil
+}
+
+// Permit returns a signed Permit2 permit letting router spend amount of owner's tokens, making
+// sure the token is approved to Permit2 first. It returns nil when router's Permit2 allowance
+// already covers amount for long enough and the swap needs no permit.
+func (a *AllowanceManager) Permit(ctx context.Context, chain string, owner, token, router common.Address, amount *big.Int) (*PermitSingle, error) {
+	client, config, err := a.Router.chain(chain)
+	if err != nil {
+		return nil, err
+	}
+	if !config.AcceptsPermit2(router) {
+		return nil, fmt.Errorf("router %s does not accept Permit2 on %s", router.Hex(), config.Name)
+	}
+	permit2 := config.Permit2Address()
+	if err := a.Ensure(ctx, config.Name, owner, token, permit2, amount); err != nil {
+		return nil, err
+	}
+
+	// Two permits signed at once would share a nonce, and the second swap would revert
+	defer a.lock(allowanceKey{config.Name, owner, token, router})()
+
+	allowance, err := GetPermit2Allowance(ctx, client, permit2, token, owner, router)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Permit2 allowance: %v", err)
+	}
+	now := time.Now()
+	if allowance.Amount.Cmp(amount) >= 0 && allowance.Expiration > uint64(now.Add(permitSignatureTTL).Unix()) {
+		return nil, nil
+	}
+
+	signer, err := a.Router.signer(owner)
+	if err != nil {
+		return nil, err
+	}
+	typedSigner, ok := signer.(TypedDataSigner)
+	if !ok {
+		return nil, fmt.Errorf("signer for %s can't sign Permit2 permits", owner.Hex())
+	}
+
+	permit := &PermitSingle{
+		Token:       token,
+		Amount:      new(big.Int).Set(MaxPermit2Amount),
+		Expiration:  uint64(now.Add(a.PermitExpiration).Unix()),
+		Nonce:       allowance.Nonce,
+		Spender:     router,
+		SigDeadline: big.NewInt(now.Add(permitSignatureTTL).Unix()),
+	}
+	permit.Signature, err = typedSigner.SignTypedData(ctx, permit.typedData(config.ChainID, permit2))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign permit: %v", err)
+	}
+	return permit, nil
+}
+
+// Revoke sets spender's allowance over owner's tokens to zero.
+func (a *AllowanceManager) Revoke(ctx context.Context, chain string, owner, token, spender common.Address) (string, error) {
+	config, err := a.Router.Chain(chain)
+	if err != nil {
+		return "", err
+	}
+	key := allowanceKey{config.Name, owner, token, spender}
+	defer a.lock(key)()
+
+	hash, err := a.Router.SendApproval(ctx, config.Name, owner, token, spender, big.NewInt(0))
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke %s for %s: %v", token.Hex(), spender.Hex(), err)
+	}
+
+	a.mu.Lock()
+	delete(a.pending, key)
+	delete(a.allowances, key)
+	a.mu.Unlock()
+
+	if err := a.DB.DeleteApproval(ctx, config.Name, owner.Hex(), token.Hex(), spender.Hex()); err != nil {
+		log.Printf("Failed to forget approval of %s for %s: %v", token.Hex(), spender.Hex(), err)
+	}
+	return hash, nil
+}
+
+// RevokeAll revokes every recorded approval on a chain that still has an allowance left, and
+// returns the revocation transaction hashes. The revocations are not waited for, the router's
+// nonce manager numbers them one after another so none replaces another. Approvals from
+// wallets without a signer are skipped.
+func (a *AllowanceManager) RevokeAll(ctx context.Context, chain string) ([]string, error) {
+	client, config, err := a.Router.chain(chain)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := a.DB.ListApprovals(ctx, config.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %v", err)
+	}
+
+	var hashes []string
+	for _, approval := range approvals {
+		owner, token, spender := common.HexToAddress(approval.Owner), common.HexToAddress(approval.Token), common.HexToAddress(approval.Spender)
+		if _, err := a.Router.signer(owner); err != nil {
+			log.Printf("Skipping approval of %s from %s: %v", approval.Token, approval.Owner, err)
+			continue
+		}
+
+		allowance, err := GetAllowance(ctx, client, token, owner, spender)
+		if err != nil {
+			return hashes, fmt.Errorf("failed to read allowance of %s for %s: %v", approval.Token, approval.Spender, err)
+		}
+		if allowance.Sign() == 0 {
+			if err := a.DB.DeleteApproval(ctx, config.Name, approval.Owner, approval.Token, approval.Spender); err != nil {
+				return hashes, fmt.Errorf("failed to forget approval: %v", err)
+			}
+			continue
+		}
+
+		hash, err := a.Revoke(ctx, config.Name, owner, token, spender)
+		if err != nil {
+			return hashes, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,200 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Broadcast modes a strategy can select.
+const (
+	BroadcastPublic  = "public"
+	BroadcastPrivate = "private"
+	BroadcastBundle  = "bundle"
+)
+
+// DefaultBundleBlocks is how many upcoming blocks a bundle is submitted for.
+const DefaultBundleBlocks = 3
+
+// Broadcaster submits signed transactions to a chain.
+type Broadcaster interface {
+	Name() string
+	Broadcast(ctx context.Context, client *ethclient.Client, tx *types.Transaction) error
+}
+
+// PublicBroadcaster sends transactions to the public mempool through the chain's own client.
+type PublicBroadcaster struct{}
+
+func (PublicBroadcaster) Name() string {
+	return BroadcastPublic
+}
+
+func (PublicBroadcaster) Broadcast(ctx context.Context, client *ethclient.Client, tx *types.Transaction) error {
+	return client.SendTransaction(ctx, tx)
+}
+
+// PrivateRPCBroadcaster sends transactions to a protected RPC endpoint that keeps them out of
+// the public mempool until they are included, so they can't be sandwiched.
+type PrivateRPCBroadcaster struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+func (b *PrivateRPCBroadcaster) Name() string {
+	return BroadcastPrivate
+}
+
+func (b *PrivateRPCBroadcaster) Broadcast(ctx context.Context, client *ethclient.Client, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %v", err)
+	}
+	var hash string
+	return postJSONRPC(ctx, b.HTTPClient, b.URL, nil, "eth_sendRawTransaction", []interface{}{hexutil.Encode(raw)}, &hash)
+}
+
+// BundleBroadcaster submits each transaction as a single transaction bundle with
+// eth_sendBundle, targeting each of the next Blocks blocks. With an AuthKey the requests are
+// signed in the X-Flashbots-Signature header, which relays use to identify the sender.
+type BundleBroadcaster struct {
+	URL        string
+	HTTPClient *http.Client
+	Blocks     uint64
+	AuthKey    *ecdsa.PrivateKey
+}
+
+type bundle struct {
+	Txs         []string `json:"txs"`
+	BlockNumber string   `json:"blockNumber"`
+}
+
+func (b *BundleBroadcaster) Name() string {
+	return BroadcastBundle
+}
+
+func (b *BundleBroadcaster) Broadcast(ctx context.Context, client *ethclient.Client, tx *types.Transaction) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %v", err)
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	for target := head + 1; target <= b.lastBlock(head); target++ {
+		params := []interface{}{bundle{
+			Txs:         []string{hexutil.Encode(raw)},
+			BlockNumber: hexutil.EncodeUint64(target),
+		}}
+		var result json.RawMessage
+		if err := postJSONRPC(ctx, b.HTTPClient, b.URL, b.AuthKey, "eth_sendBundle", params, &result); err != nil {
+			return fmt.Errorf("failed to send bundle for block %d: %v", target, err)
+		}
+	}
+	return nil
+}
+
+// lastBlock returns the last block a bundle submitted at head targets.
+func (b *BundleBroadcaster) lastBlock(head uint64) uint64 {
+	if b.Blocks == 0 {
+		return head + DefaultBundleBlocks
+	}
+	return head + b.Blocks
+}
+
+// postJSONRPC makes a single JSON-RPC call over HTTP, signing the body with authKey if set.
+func postJSONRPC(ctx context.Context, httpClient *http.Client, url string, authKey *ecdsa.PrivateKey, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authKey != nil {
+		digest := accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(body))))
+		signature, err := crypto.Sign(digest, authKey)
+		if err != nil {
+			return fmt.Errorf("failed to sign request: %v", err)
+		}
+		req.Header.Set("X-Flashbots-Signature", crypto.PubkeyToAddress(authKey.PublicKey).Hex()+":"+hexutil.Encode(signature))
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(response))
+	}
+	return decodeResult(response, result)
+}
+
+// AddBroadcaster registers a broadcaster under its name, replacing any registered before.
+func (m *MultiChainRouter) AddBroadcaster(broadcaster Broadcaster) {
+	m.Broadcasters[broadcaster.Name()] = broadcaster
+}
+
+// InclusionDeadline returns the last block a transaction just submitted through the named
+// broadcast mode may be included in, or zero if it stays valid until mined or dropped. Past the
+// deadline a bundle that was not included never will be.
+func (m *MultiChainRouter) InclusionDeadline(ctx context.Context, chainName, mode string) (uint64, error) {
+	broadcaster, err := m.broadcaster(mode)
+	if err != nil {
+		return 0, err
+	}
+	bundles, ok := broadcaster.(*BundleBroadcaster)
+	if !ok {
+		return 0, nil
+	}
+	client, err := m.Client(chainName)
+	if err != nil {
+		return 0, err
+	}
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %v", err)
+	}
+	return bundles.lastBlock(head), nil
+}
+
+// broadcaster returns the broadcaster for a mode, the public mempool when mode is empty.
+func (m *MultiChainRouter) broadcaster(mode string) (Broadcaster, error) {
+	if mode == "" || mode == BroadcastPublic {
+		return PublicBroadcaster{}, nil
+	}
+	broadcaster, ok := m.Broadcasters[mode]
+	if !ok {
+		return nil, fmt.Errorf("no %s broadcaster configured", mode)
+	}
+	return broadcaster, nil
+}
@@ -0,0 +1,255 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// relayRequest is one JSON-RPC request received by relayStub, with its signature header.
+type relayRequest struct {
+	Method    string
+	Params    []json.RawMessage
+	Body      []byte
+	Signature string
+}
+
+// relayStub stands in for a private RPC or a bundle relay. It records every request and answers
+// with result, or with rpcError when set.
+type relayStub struct {
+	mu       sync.Mutex
+	requests []relayRequest
+	result   any
+	rpcError string
+}
+
+func (s *relayStub) serve(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request: %v", err)
+			return
+		}
+		var request struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("bad relay request %s: %v", body, err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, relayRequest{
+			Method:    request.Method,
+			Params:    request.Params,
+			Body:      body,
+			Signature: r.Header.Get("X-Flashbots-Signature"),
+		})
+		s.mu.Unlock()
+
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+		if s.rpcError != "" {
+			response["error"] = map[string]any{"code": -32000, "message": s.rpcError}
+		} else {
+			response["result"] = s.result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// headClient returns a chain client whose node reports head as the latest block.
+func headClient(t *testing.T, head uint64) *ethclient.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.Method != "eth_blockNumber" {
+			t.Errorf("unexpected chain call %s", request.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": hexutil.EncodeUint64(head)})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func signedTestTx(t *testing.T) *types.Transaction {
+	to := common.HexToAddress("0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24")
+	tx, err := types.SignNewTx(newTestKey(t), types.LatestSignerForChainID(big.NewInt(8453)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(8453),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1_000_000),
+		GasFeeCap: big.NewInt(1_000_000_000),
+		Gas:       210_000,
+		To:        &to,
+		Value:     big.NewInt(1e15),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestPrivateRPCBroadcasterSendsRawTransaction(t *testing.T) {
+	tx := signedTestTx(t)
+	relay := &relayStub{result: tx.Hash().Hex()}
+	server := relay.serve(t)
+
+	broadcaster := &PrivateRPCBroadcaster{URL: server.URL}
+	if err := broadcaster.Broadcast(context.Background(), nil, tx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(relay.requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(relay.requests))
+	}
+	request := relay.requests[0]
+	if request.Method != "eth_sendRawTransaction" || len(request.Params) != 1 {
+		t.Fatalf("unexpected request %s", request.Body)
+	}
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(request.Params[0], &raw); err != nil {
+		t.Fatalf("params are not a raw transaction: %v", err)
+	}
+	sent := new(types.Transaction)
+	if err := sent.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("failed to decode sent transaction: %v", err)
+	}
+	if sent.Hash() != tx.Hash() {
+		t.Fatalf("sent %s, want %s", sent.Hash().Hex(), tx.Hash().Hex())
+	}
+	if request.Signature != "" {
+		t.Fatalf("private RPC request should not be signed, got %q", request.Signature)
+	}
+}
+
+func TestPrivateRPCBroadcasterReturnsRPCError(t *testing.T) {
+	relay := &relayStub{rpcError: "nonce too low"}
+	server := relay.serve(t)
+
+	broadcaster := &PrivateRPCBroadcaster{URL: server.URL}
+	err := broadcaster.Broadcast(context.Background(), nil, signedTestTx(t))
+	if err == nil || !strings.Contains(err.Error(), "nonce too low") {
+		t.Fatalf("expected the RPC error, got %v", err)
+	}
+}
+
+func TestPrivateRPCBroadcasterReturnsHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	broadcaster := &PrivateRPCBroadcaster{URL: server.URL}
+	err := broadcaster.Broadcast(context.Background(), nil, signedTestTx(t))
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("expected the HTTP error, got %v", err)
+	}
+}
+
+func TestBundleBroadcasterTargetsEachBlock(t *testing.T) {
+	const head = 1000
+	tx := signedTestTx(t)
+	authKey := newTestKey(t)
+	relay := &relayStub{result: map[string]string{"bundleHash": "0x01"}}
+	server := relay.serve(t)
+
+	broadcaster := &BundleBroadcaster{URL: server.URL, Blocks: 4, AuthKey: authKey}
+	if err := broadcaster.Broadcast(context.Background(), headClient(t, head), tx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(relay.requests) != 4 {
+		t.Fatalf("expected one bundle per target block, got %d", len(relay.requests))
+	}
+	authAddress := crypto.PubkeyToAddress(authKey.PublicKey)
+	for i, request := range relay.requests {
+		if request.Method != "eth_sendBundle" || len(request.Params) != 1 {
+			t.Fatalf("unexpected request %s", request.Body)
+		}
+		var sent bundle
+		if err := json.Unmarshal(request.Params[0], &sent); err != nil {
+			t.Fatalf("params are not a bundle: %v", err)
+		}
+		if want := hexutil.EncodeUint64(head + 1 + uint64(i)); sent.BlockNumber != want {
+			t.Fatalf("bundle %d targets %s, want %s", i, sent.BlockNumber, want)
+		}
+		raw, _ := tx.MarshalBinary()
+		if len(sent.Txs) != 1 || sent.Txs[0] != hexutil.Encode(raw) {
+			t.Fatalf("bundle %d carries %v", i, sent.Txs)
+		}
+
+		// The header is the signer's address and its signature over the hex keccak of the body
+		address, signature, ok := strings.Cut(request.Signature, ":")
+		if !ok || common.HexToAddress(address) != authAddress {
+			t.Fatalf("bundle %d signed as %q, want %s", i, request.Signature, authAddress.Hex())
+		}
+		digest := accounts.TextHash([]byte(hexutil.Encode(crypto.Keccak256(request.Body))))
+		pub, err := crypto.SigToPub(digest, hexutil.MustDecode(signature))
+		if err != nil {
+			t.Fatalf("bundle %d has an unreadable signature: %v", i, err)
+		}
+		if crypto.PubkeyToAddress(*pub) != authAddress {
+			t.Fatalf("bundle %d signature does not recover to %s", i, authAddress.Hex())
+		}
+	}
+}
+
+func TestBundleBroadcasterDefaultsBlocks(t *testing.T) {
+	relay := &relayStub{result: map[string]string{"bundleHash": "0x01"}}
+	server := relay.serve(t)
+
+	broadcaster := &BundleBroadcaster{URL: server.URL}
+	if err := broadcaster.Broadcast(context.Background(), headClient(t, 50), signedTestTx(t)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(relay.requests) != DefaultBundleBlocks {
+		t.Fatalf("expected %d bundles, got %d", DefaultBundleBlocks, len(relay.requests))
+	}
+	for _, request := range relay.requests {
+		if request.Signature != "" {
+			t.Fatalf("bundle without an auth key should not be signed, got %q", request.Signature)
+		}
+	}
+}
+
+func TestBundleBroadcasterReturnsRPCError(t *testing.T) {
+	relay := &relayStub{rpcError: "bundle simulation failed"}
+	server := relay.serve(t)
+
+	broadcaster := &BundleBroadcaster{URL: server.URL, Blocks: 3}
+	err := broadcaster.Broadcast(context.Background(), headClient(t, 10), signedTestTx(t))
+	if err == nil || !strings.Contains(err.Error(), "bundle simulation failed") || !strings.Contains(err.Error(), "block 11") {
+		t.Fatalf("expected the relay error for the first block, got %v", err)
+	}
+	if len(relay.requests) != 1 {
+		t.Fatalf("expected to stop at the first rejected bundle, got %d requests", len(relay.requests))
+	}
+}
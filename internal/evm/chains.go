@@ -0,0 +1,203 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultCallTimeout bounds RPC calls on chains configured without a call timeout.
+const DefaultCallTimeout = 15 * time.Second
+
+// Duration is a time.Duration written in JSON as a string such as "10s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10s\": %v", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// DEXUniswapV2 is the Routers and Factories key of Uniswap V2, which copy trades are made on.
+const DEXUniswapV2 = "uniswap_v2"
+
+// RouterUniversal is the Routers key of Uniswap's Universal Router. Sells through it spend the
+// token with a signed Permit2 permit instead of a router allowance.
+const RouterUniversal = "universal_router"
+
+// ChainConfig describes a chain the router can trade on. Routers and factories are keyed by
+// DEX name, such as "uniswap_v2".
+type ChainConfig struct {
+	Name    string   `json:"name"`
+	ChainID *big.Int `json:"chain_id"`
+	RPCURL  string   `json:"rpc_url,omitempty"`
+	// RPCURLs are further endpoints pooled with RPCURL for failover.
+	RPCURLs   []string                  `json:"rpc_urls,omitempty"`
+	WSURL     string                    `json:"ws_url,omitempty"`
+	WETH      common.Address            `json:"weth"`
+	Routers   map[string]common.Address `json:"routers,omitempty"`
+	Factories map[string]common.Address `json:"factories,omitempty"`
+	Explorers []string                  `json:"explorers,omitempty"`
+	// Permit2 overrides the canonical Permit2 contract address.
+	Permit2 common.Address `json:"permit2,omitempty"`
+	// CallTimeout bounds each RPC request to the chain's nodes, and each router call as a
+	// whole, DefaultCallTimeout when zero.
+	CallTimeout Duration `json:"call_timeout,omitempty"`
+}
+
+// LoadChainConfigs reads a JSON array of chain configs from a file, for example
+//
+//	[{"name": "base", "chain_id": 8453, "rpc_url": "https://mainnet.base.org",
+//	  "weth": "0x4200000000000000000000000000000000000006",
+//	  "routers": {"uniswap_v2": "0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24",
+//	              "universal_router": "0x3fC91A3afd70395Cd496C647d5a6CC9D4B2b7FAD"},
+//	  "factories": {"uniswap_v2": "0x8909Dc15e40173Ff4699343b6eB8132c65e18eC6"},
+//	  "explorers": ["https://basescan.org"], "call_timeout": "10s"}]
+func LoadChainConfigs(path string) ([]*ChainConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain configs: %v", err)
+	}
+
+	var configs []*ChainConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse chain configs %s: %v", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, config := range configs {
+		if config.Name == "" || config.ChainID == nil || len(config.Endpoints()) == 0 {
+			return nil, fmt.Errorf("chain config %d needs a name, chain_id and rpc_url or rpc_urls", i)
+		}
+		id := config.ChainID.String()
+		if seen[config.Name] || seen[id] {
+			return nil, fmt.Errorf("chain %s (%s) is configured twice", config.Name, id)
+		}
+		seen[config.Name], seen[id] = true, true
+	}
+	return configs, nil
+}
+
+// Endpoints returns every RPC URL of the chain.
+func (c *ChainConfig) Endpoints() []string {
+	var urls []string
+	if c.RPCURL != "" {
+		urls = append(urls, c.RPCURL)
+	}
+	return append(urls, c.RPCURLs...)
+}
+
+// Timeout returns the chain's call timeout.
+func (c *ChainConfig) Timeout() time.Duration {
+	if c.CallTimeout > 0 {
+		return time.Duration(c.CallTimeout)
+	}
+	return DefaultCallTimeout
+}
+
+// UniswapV2Router returns the chain's Uniswap V2 router, the zero address if none is configured.
+func (c *ChainConfig) UniswapV2Router() common.Address {
+	return c.Routers[DEXUniswapV2]
+}
+
+// UniswapV2Factory returns the chain's Uniswap V2 factory, the zero address if none is configured.
+func (c *ChainConfig) UniswapV2Factory() common.Address {
+	return c.Factories[DEXUniswapV2]
+}
+
+// Explorer returns the chain's first block explorer, empty if none is configured.
+func (c *ChainConfig) Explorer() string {
+	if len(c.Explorers) == 0 {
+		return ""
+	}
+	return strings.TrimRight(c.Explorers[0], "/")
+}
+
+// Permit2Address returns the Permit2 contract of the chain.
+func (c *ChainConfig) Permit2Address() common.Address {
+	if c.Permit2 != (common.Address{}) {
+		return c.Permit2
+	}
+	return Permit2Address
+}
+
+// AcceptsPermit2 reports whether a router takes Permit2 permits, which only the Universal
+// Router configured under RouterUniversal does.
+func (c *ChainConfig) AcceptsPermit2(router common.Address) bool {
+	universal, ok := c.Routers[RouterUniversal]
+	return ok && router != (common.Address{}) && router == universal
+}
+
+// verifyChainID checks that the node at the end of client serves the configured chain, so a
+// wrong RPC URL can't sign transactions for one chain and send them to another.
+func verifyChainID(client *ethclient.Client, config *ChainConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout())
+	defer cancel()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain ID of %s: %v", config.Name, err)
+	}
+	if chainID.Cmp(config.ChainID) != 0 {
+		return fmt.Errorf("chain %s is configured with ID %s but its node serves chain %s", config.Name, config.ChainID, chainID)
+	}
+	return nil
+}
+
+// Chain returns the config of a chain by name or decimal chain ID.
+func (m *MultiChainRouter) Chain(nameOrID string) (*ChainConfig, error) {
+	if config, ok := m.Chains[nameOrID]; ok {
+		return config, nil
+	}
+	if id, err := strconv.ParseUint(nameOrID, 10, 64); err == nil {
+		return m.ChainByID(id)
+	}
+	return nil, fmt.Errorf("unsupported chain: %s", nameOrID)
+}
+
+// ChainByID returns the config of a chain by its chain ID.
+func (m *MultiChainRouter) ChainByID(id uint64) (*ChainConfig, error) {
+	for _, config := range m.Chains {
+		if config.ChainID.IsUint64() && config.ChainID.Uint64() == id {
+			return config, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported chain ID: %d", id)
+}
+
+// Client returns the client connected to a chain, looked up by name or decimal chain ID.
+func (m *MultiChainRouter) Client(nameOrID string) (*ethclient.Client, error) {
+	config, err := m.Chain(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	return m.Clients[config.Name], nil
+}
+
+// chain returns the client and config of a chain, looked up by name or decimal chain ID.
+func (m *MultiChainRouter) chain(nameOrID string) (*ethclient.Client, *ChainConfig, error) {
+	config, err := m.Chain(nameOrID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.Clients[config.Name], config, nil
+}
@@ -0,0 +1,107 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNoContract is returned when there is no code at an address.
+var ErrNoContract = errors.New("no contract code at address")
+
+// ContractCreation describes when and by whom a contract was deployed.
+type ContractCreation struct {
+	Block    uint64
+	Time     time.Time
+	TxHash   common.Hash
+	Deployer common.Address
+	// Factory is the contract that created this one, zero for direct deployments.
+	Factory common.Address
+}
+
+// FindContractCreation locates the block a contract was deployed in by binary searching for the
+// first block with code at the address, then finds the deploying transaction in that block.
+// It needs an archive node for historical code lookups. Deployer is the sender of the deploying
+// transaction, which for factory deployments is the account that called the factory.
+func FindContractCreation(ctx context.Context, client *ethclient.Client, address common.Address) (*ContractCreation, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	code, err := client.CodeAt(ctx, address, new(big.Int).SetUint64(head))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code: %v", err)
+	}
+	if len(code) == 0 {
+		return nil, ErrNoContract
+	}
+
+	low, high := uint64(0), head
+	for low < high {
+		mid := low + (high-low)/2
+		code, err := client.CodeAt(ctx, address, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get code at block %d: %v", mid, err)
+		}
+		if len(code) > 0 {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+
+	block, err := fetchBlock(ctx, client, new(big.Int).SetUint64(low))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get creation block %d: %v", low, err)
+	}
+
+	creation := &ContractCreation{
+		Block: low,
+		Time:  time.Unix(int64(block.Timestamp), 0),
+	}
+
+	receipts, err := client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(low)))
+	if err != nil {
+		// The block and time are still useful without the deployer
+		return creation, nil
+	}
+
+	for i, receipt := range receipts {
+		deployed := receipt.ContractAddress == address
+		if !deployed {
+			for _, l := range receipt.Logs {
+				if l.Address == address {
+					deployed = true
+					break
+				}
+			}
+		}
+		if !deployed || i >= len(block.Transactions) {
+			continue
+		}
+
+		var sender struct {
+			From common.Address  `json:"from"`
+			To   *common.Address `json:"to"`
+		}
+		if err := json.Unmarshal(block.Transactions[i], &sender); err != nil {
+			continue
+		}
+		creation.TxHash = receipt.TxHash
+		creation.Deployer = sender.From
+		if receipt.ContractAddress != address && sender.To != nil {
+			creation.Factory = *sender.To
+		}
+		break
+	}
+
+	return creation, nil
+}
@@ -0,0 +1,126 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrSwapReverted is returned for swaps that were mined but failed.
+var ErrSwapReverted = errors.New("swap reverted")
+
+// SwapFill is what a mined swap delivered to a wallet.
+type SwapFill struct {
+	Block     uint64
+	BlockTime time.Time
+	// Tokens is the amount of the token transferred to the wallet, in base units.
+	Tokens *big.Int
+}
+
+// withdrawalTopic is WETH's Withdrawal(address,uint256) event, logged when the router unwraps
+// the WETH a sell bought to pay the seller in ETH.
+var withdrawalTopic = crypto.Keccak256Hash([]byte("Withdrawal(address,uint256)"))
+
+// WaitForFill polls for a swap's receipt until it is mined or ctx is done, then sums the
+// token transfers it made to wallet. Transactions that are already mined return at once.
+func WaitForFill(ctx context.Context, client *ethclient.Client, hash common.Hash, token, wallet common.Address, pollInterval time.Duration) (*SwapFill, error) {
+	receipt, err := waitForReceipt(ctx, client, hash, pollInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header %s: %v", receipt.BlockNumber, err)
+	}
+
+	fill := &SwapFill{
+		Block:     receipt.BlockNumber.Uint64(),
+		BlockTime: time.Unix(int64(header.Time), 0),
+		Tokens:    new(big.Int),
+	}
+	for _, l := range receipt.Logs {
+		if l.Address != token || len(l.Topics) != 3 || l.Topics[0] != transferTopic {
+			continue
+		}
+		if common.BytesToAddress(l.Topics[2].Bytes()) == wallet {
+			fill.Tokens.Add(fill.Tokens, new(big.Int).SetBytes(l.Data))
+		}
+	}
+	return fill, nil
+}
+
+// WaitForSale polls for a sell's receipt like WaitForFill and returns the ETH it paid out, in
+// wei, which is the WETH the swap unwrapped.
+func WaitForSale(ctx context.Context, client *ethclient.Client, hash common.Hash, weth common.Address, pollInterval time.Duration) (*big.Int, error) {
+	receipt, err := waitForReceipt(ctx, client, hash, pollInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	received := new(big.Int)
+	for _, l := range receipt.Logs {
+		if l.Address == weth && len(l.Topics) == 2 && l.Topics[0] == withdrawalTopic {
+			received.Add(received, new(big.Int).SetBytes(l.Data))
+		}
+	}
+	return received, nil
+}
+
+// waitForReceipt polls for a swap's receipt until it is mined or ctx is done, and returns
+// ErrSwapReverted if it failed.
+func waitForReceipt(ctx context.Context, client *ethclient.Client, hash common.Hash, pollInterval time.Duration) (*types.Receipt, error) {
+	for {
+		receipt, err := client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return nil, fmt.Errorf("%s: %w", hash.Hex(), ErrSwapReverted)
+			}
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, fmt.Errorf("failed to get receipt for %s: %v", hash.Hex(), err)
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// WaitForInclusion polls until a transaction is mined, reporting true, or the chain is past
+// lastBlock without it, reporting false. It is for transactions that can only be included up to
+// a block, such as bundles.
+func WaitForInclusion(ctx context.Context, client *ethclient.Client, hash common.Hash, lastBlock uint64, pollInterval time.Duration) (bool, error) {
+	for {
+		// Read the head first, so a receipt missing after it means the swap missed lastBlock
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to get block number: %v", err)
+		}
+		_, err = client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return false, fmt.Errorf("failed to get receipt for %s: %v", hash.Hex(), err)
+		}
+		if head > lastBlock {
+			return false, nil
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ErrInvalidMnemonic is returned for mnemonics that fail the BIP-39 checksum.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// HDWallet derives follower sub-wallets from a single BIP-39 mnemonic along BIP-44 paths.
+type HDWallet struct {
+	masterKey       []byte
+	masterChainCode []byte
+}
+
+// NewMnemonic generates a fresh 24 word BIP-39 mnemonic.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(256)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NewHDWallet builds the BIP-32 master key from a mnemonic and optional BIP-39 passphrase.
+func NewHDWallet(mnemonic, passphrase string) (*HDWallet, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+
+	seed := bip39.NewSeed(mnemonic, passphrase)
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	return &HDWallet{
+		masterKey:       sum[:32],
+		masterChainCode: sum[32:],
+	}, nil
+}
+
+// SubWalletPath returns the BIP-44 path of the i-th follower sub-wallet, m/44'/60'/0'/0/i.
+func SubWalletPath(index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(accounts.DefaultRootDerivationPath), len(accounts.DefaultRootDerivationPath)+1)
+	copy(path, accounts.DefaultRootDerivationPath)
+	return append(path, index)
+}
+
+// Derive returns the private key and address of the i-th follower sub-wallet.
+func (w *HDWallet) Derive(index uint32) (*ecdsa.PrivateKey, common.Address, error) {
+	key, err := w.DerivePath(SubWalletPath(index))
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return key, crypto.PubkeyToAddress(key.PublicKey), nil
+}
+
+// DerivePath walks a BIP-32 derivation path from the master key.
+func (w *HDWallet) DerivePath(path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	key := new(big.Int).SetBytes(w.masterKey)
+	chainCode := w.masterChainCode
+	curveOrder := crypto.S256().Params().N
+
+	for _, index := range path {
+		data := make([]byte, 0, 37)
+		if index >= 0x80000000 {
+			// Hardened child: 0x00 || ser256(k_par) || ser32(i)
+			data = append(data, 0)
+			data = append(data, math.PaddedBigBytes(key, 32)...)
+		} else {
+			// Normal child: serP(point(k_par)) || ser32(i)
+			parent, err := crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+			if err != nil {
+				return nil, fmt.Errorf("invalid parent key: %v", err)
+			}
+			data = append(data, crypto.CompressPubkey(&parent.PublicKey)...)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(curveOrder) >= 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		key = tweak.Add(tweak, key)
+		key.Mod(key, curveOrder)
+		if key.Sign() == 0 {
+			return nil, fmt.Errorf("invalid child key at index %d", index)
+		}
+		chainCode = sum[32:]
+	}
+
+	return crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+}
@@ -0,0 +1,283 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultLogRange is the block span of each FilterLogs query, which most RPC providers cap.
+const DefaultLogRange = 2000
+
+// transferTopic is the ERC20 Transfer(address,address,uint256) event signature.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// FindLeaderSwaps returns the router swaps a leader sent between two blocks, oldest first.
+// Swaps are found through the ERC20 transfers into and out of the leader's wallet, so every
+// swap moves tokens and shows up without scanning whole blocks.
+func FindLeaderSwaps(ctx context.Context, client *ethclient.Client, chain string, leader common.Address, fromBlock, toBlock, logRange uint64) ([]*Signal, error) {
+	if logRange == 0 {
+		logRange = DefaultLogRange
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	signer := types.LatestSignerForChainID(chainID)
+
+	leaderTopic := common.BytesToHash(leader.Bytes())
+	type txRef struct {
+		block uint64
+		index uint
+	}
+	txs := make(map[common.Hash]txRef)
+
+	for start := fromBlock; start <= toBlock; start += logRange {
+		end := start + logRange - 1
+		if end > toBlock {
+			end = toBlock
+		}
+		for _, topics := range [][][]common.Hash{
+			{{transferTopic}, {leaderTopic}},
+			{{transferTopic}, nil, {leaderTopic}},
+		} {
+			logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(start),
+				ToBlock:   new(big.Int).SetUint64(end),
+				Topics:    topics,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to filter logs in blocks %d-%d: %v", start, end, err)
+			}
+			for _, l := range logs {
+				txs[l.TxHash] = txRef{block: l.BlockNumber, index: l.TxIndex}
+			}
+		}
+	}
+
+	hashes := make([]common.Hash, 0, len(txs))
+	for hash := range txs {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := txs[hashes[i]], txs[hashes[j]]
+		if a.block != b.block {
+			return a.block < b.block
+		}
+		return a.index < b.index
+	})
+
+	headers := make(map[uint64]*types.Header)
+	var signals []*Signal
+	for _, hash := range hashes {
+		tx, _, err := client.TransactionByHash(ctx, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %s: %v", hash.Hex(), err)
+		}
+		// Transfers into the wallet may come from anyone's transaction
+		if from, err := types.Sender(signer, tx); err != nil || from != leader {
+			continue
+		}
+
+		signal, err := DecodeSwap(tx, leader)
+		if errors.Is(err, ErrNotASwap) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode swap %s: %v", hash.Hex(), err)
+		}
+
+		number := txs[hash].block
+		header, ok := headers[number]
+		if !ok {
+			header, err = client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get header %d: %v", number, err)
+			}
+			headers[number] = header
+		}
+
+		signal.Chain = chain
+		signal.BlockNumber = number
+		signal.BlockHash = header.Hash()
+		signal.BlockTime = time.Unix(int64(header.Time), 0)
+		signals = append(signals, signal)
+	}
+
+	return signals, nil
+}
+
+// swapTopic is the Uniswap V2 pair Swap event signature.
+var swapTopic = crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)"))
+
+// senderBatchSize is how many transaction senders are looked up per batch request.
+const senderBatchSize = 100
+
+// PairSwap is a token/WETH swap on a Uniswap V2 pair, attributed to the wallet that sent it.
+type PairSwap struct {
+	Pair        common.Address
+	Token       common.Address
+	Trader      common.Address
+	Side        string
+	ETHAmount   *big.Int
+	TokenAmount *big.Int
+	BlockNumber uint64
+	TxHash      common.Hash
+	LogIndex    uint
+}
+
+// FindPairSwaps returns the swaps on token/WETH pairs between two blocks, oldest first. Traders
+// are the senders of the swapping transactions rather than the Swap recipient, which is the
+// router when selling for ETH. Pairs without WETH are ignored.
+func FindPairSwaps(ctx context.Context, client *ethclient.Client, pairs []common.Address, weth common.Address, fromBlock, toBlock, logRange uint64) ([]*PairSwap, error) {
+	if logRange == 0 {
+		logRange = DefaultLogRange
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(pairABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pair ABI: %v", err)
+	}
+
+	// wethIsToken0 records which side of each pair is WETH, and the token on the other side
+	wethIsToken0 := make(map[common.Address]bool)
+	tokens := make(map[common.Address]common.Address)
+	var watched []common.Address
+	for _, pair := range pairs {
+		token0, err := callView(ctx, client, parsedABI, pair, nil, "token0")
+		if err != nil {
+			return nil, err
+		}
+		token1, err := callView(ctx, client, parsedABI, pair, nil, "token1")
+		if err != nil {
+			return nil, err
+		}
+		switch weth {
+		case token0[0].(common.Address):
+			wethIsToken0[pair] = true
+			tokens[pair] = token1[0].(common.Address)
+		case token1[0].(common.Address):
+			tokens[pair] = token0[0].(common.Address)
+		default:
+			continue
+		}
+		watched = append(watched, pair)
+	}
+	if len(watched) == 0 {
+		return nil, nil
+	}
+
+	var swaps []*PairSwap
+	for start := fromBlock; start <= toBlock; start += logRange {
+		end := start + logRange - 1
+		if end > toBlock {
+			end = toBlock
+		}
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: watched,
+			Topics:    [][]common.Hash{{swapTopic}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter logs in blocks %d-%d: %v", start, end, err)
+		}
+
+		for _, l := range logs {
+			if l.Removed || len(l.Data) < 128 {
+				continue
+			}
+			amount0In := new(big.Int).SetBytes(l.Data[0:32])
+			amount1In := new(big.Int).SetBytes(l.Data[32:64])
+			amount0Out := new(big.Int).SetBytes(l.Data[64:96])
+			amount1Out := new(big.Int).SetBytes(l.Data[96:128])
+
+			wethIn, tokenIn, wethOut, tokenOut := amount1In, amount0In, amount1Out, amount0Out
+			if wethIsToken0[l.Address] {
+				wethIn, tokenIn, wethOut, tokenOut = amount0In, amount1In, amount0Out, amount1Out
+			}
+
+			swap := &PairSwap{
+				Pair:        l.Address,
+				Token:       tokens[l.Address],
+				BlockNumber: l.BlockNumber,
+				TxHash:      l.TxHash,
+				LogIndex:    l.Index,
+			}
+			switch {
+			case wethIn.Sign() > 0 && tokenOut.Sign() > 0:
+				swap.Side, swap.ETHAmount, swap.TokenAmount = SideBuy, wethIn, tokenOut
+			case tokenIn.Sign() > 0 && wethOut.Sign() > 0:
+				swap.Side, swap.ETHAmount, swap.TokenAmount = SideSell, wethOut, tokenIn
+			default:
+				continue
+			}
+			swaps = append(swaps, swap)
+		}
+	}
+
+	if err := resolveTraders(ctx, client, swaps); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(swaps, func(i, j int) bool {
+		if swaps[i].BlockNumber != swaps[j].BlockNumber {
+			return swaps[i].BlockNumber < swaps[j].BlockNumber
+		}
+		return swaps[i].LogIndex < swaps[j].LogIndex
+	})
+	return swaps, nil
+}
+
+// resolveTraders fills in the sender of each swap's transaction with batched requests.
+func resolveTraders(ctx context.Context, client *ethclient.Client, swaps []*PairSwap) error {
+	senders := make(map[common.Hash]*rpcTxSender)
+	var hashes []common.Hash
+	for _, swap := range swaps {
+		if _, ok := senders[swap.TxHash]; !ok {
+			senders[swap.TxHash] = new(rpcTxSender)
+			hashes = append(hashes, swap.TxHash)
+		}
+	}
+
+	for start := 0; start < len(hashes); start += senderBatchSize {
+		end := start + senderBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch := make([]rpc.BatchElem, 0, end-start)
+		for _, hash := range hashes[start:end] {
+			batch = append(batch, rpc.BatchElem{
+				Method: "eth_getTransactionByHash",
+				Args:   []interface{}{hash},
+				Result: senders[hash],
+			})
+		}
+		if err := client.Client().BatchCallContext(ctx, batch); err != nil {
+			return fmt.Errorf("failed to look up transaction senders: %v", err)
+		}
+		for _, elem := range batch {
+			if elem.Error != nil {
+				return fmt.Errorf("failed to look up transaction sender: %v", elem.Error)
+			}
+		}
+	}
+
+	for _, swap := range swaps {
+		swap.Trader = senders[swap.TxHash].From
+	}
+	return nil
+}
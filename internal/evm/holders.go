@@ -0,0 +1,61 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrHolderCountUnavailable is returned when the explorer does not know a token's holder count.
+var ErrHolderCountUnavailable = errors.New("holder count unavailable")
+
+// FetchHolderCount reads a token's holder count from a Blockscout compatible explorer API,
+// such as https://base.blockscout.com.
+func FetchHolderCount(ctx context.Context, httpClient *http.Client, apiURL string, token common.Address) (uint64, error) {
+	type CountersResponse struct {
+		TokenHoldersCount string `json:"token_holders_count"`
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	url := fmt.Sprintf("%s/api/v2/tokens/%s/counters", strings.TrimRight(apiURL, "/"), token.Hex())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, ErrHolderCountUnavailable
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("holders: unexpected status %s", resp.Status)
+	}
+
+	var counters CountersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&counters); err != nil {
+		return 0, err
+	}
+	if counters.TokenHoldersCount == "" {
+		return 0, ErrHolderCountUnavailable
+	}
+
+	count, err := strconv.ParseUint(counters.TokenHoldersCount, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("holders: invalid count %q", counters.TokenHoldersCount)
+	}
+	return count, nil
+}
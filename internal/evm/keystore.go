@@ -0,0 +1,131 @@
+package evm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrKeyLocked is returned when a key is requested for an address that has not been unlocked.
+var ErrKeyLocked = errors.New("key is locked or unknown")
+
+// KeyStore keeps follower wallet keys encrypted on disk (scrypt JSON, as written by geth)
+// and holds them decrypted in memory only once they have been unlocked.
+type KeyStore struct {
+	Dir string
+
+	ks   *keystore.KeyStore
+	mu   sync.RWMutex
+	keys map[common.Address]*ecdsa.PrivateKey
+}
+
+// OpenKeyStore opens, or creates, the encrypted keystore directory.
+func OpenKeyStore(dir string) *KeyStore {
+	return &KeyStore{
+		Dir:  dir,
+		ks:   keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP),
+		keys: make(map[common.Address]*ecdsa.PrivateKey),
+	}
+}
+
+// NewAccount generates a fresh key and stores it encrypted with passphrase.
+func (k *KeyStore) NewAccount(passphrase string) (common.Address, error) {
+	account, err := k.ks.NewAccount(passphrase)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to create account: %v", err)
+	}
+	return account.Address, nil
+}
+
+// ImportHexKey encrypts an existing hex private key into the keystore.
+func (k *KeyStore) ImportHexKey(privateKey, passphrase string) (common.Address, error) {
+	privateKeyECDSA, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	return k.ImportECDSA(privateKeyECDSA, passphrase)
+}
+
+// ImportECDSA encrypts an existing private key into the keystore.
+func (k *KeyStore) ImportECDSA(privateKey *ecdsa.PrivateKey, passphrase string) (common.Address, error) {
+	account, err := k.ks.ImportECDSA(privateKey, passphrase)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to import key: %v", err)
+	}
+	return account.Address, nil
+}
+
+// Accounts lists the addresses stored in the keystore.
+func (k *KeyStore) Accounts() []common.Address {
+	stored := k.ks.Accounts()
+	addresses := make([]common.Address, len(stored))
+	for i, account := range stored {
+		addresses[i] = account.Address
+	}
+	return addresses
+}
+
+// Unlock decrypts the key for address and keeps it in memory.
+func (k *KeyStore) Unlock(address common.Address, passphrase string) error {
+	account, err := k.ks.Find(accounts.Account{Address: address})
+	if err != nil {
+		return fmt.Errorf("failed to find account %s: %v", address.Hex(), err)
+	}
+
+	keyJSON, err := os.ReadFile(account.URL.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read key file for %s: %v", address.Hex(), err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt key for %s: %v", address.Hex(), err)
+	}
+
+	k.mu.Lock()
+	k.keys[address] = key.PrivateKey
+	k.mu.Unlock()
+
+	return nil
+}
+
+// UnlockAll decrypts every key in the keystore with the same passphrase.
+func (k *KeyStore) UnlockAll(passphrase string) error {
+	for _, address := range k.Accounts() {
+		if err := k.Unlock(address, passphrase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Key returns the unlocked private key for address.
+func (k *KeyStore) Key(address common.Address) (*ecdsa.PrivateKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[address]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", address.Hex(), ErrKeyLocked)
+	}
+	return key, nil
+}
+
+// Lock wipes every decrypted key from memory.
+func (k *KeyStore) Lock() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for address, key := range k.keys {
+		key.D.SetInt64(0)
+		delete(k.keys, address)
+	}
+}
@@ -0,0 +1,109 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	// ErrInsufficientLiquidity is returned when a pool holds less than the configured floor.
+	ErrInsufficientLiquidity = errors.New("pool liquidity below configured floor")
+	// ErrPriceImpactTooHigh is returned when a swap would move the price more than allowed.
+	ErrPriceImpactTooHigh = errors.New("price impact above configured ceiling")
+)
+
+// LiquidityGuard rejects or downsizes buys into pools that are too thin for our trade size.
+type LiquidityGuard struct {
+	// MinLiquidityETH is the minimum total pool value in ETH (both sides), 0 to disable.
+	MinLiquidityETH float64
+	// MinLiquidityUSD is the minimum total pool value in USD (both sides), 0 to disable.
+	MinLiquidityUSD float64
+	// MaxPriceImpact is the highest acceptable price impact in percent, 0 to disable.
+	MaxPriceImpact float64
+	// Downsize shrinks the trade to fit MaxPriceImpact instead of rejecting it.
+	Downsize bool
+	// Prices values the pool in USD; required when MinLiquidityUSD is set.
+	Prices PriceSource
+}
+
+// CalculatePriceImpact returns the price impact in percent of swapping amountIn against
+// a constant product pool, measured against the mid price and excluding the LP fee.
+func CalculatePriceImpact(amountIn, reserveIn *big.Int) float64 {
+	if amountIn.Sign() <= 0 {
+		return 0
+	}
+	denominator := new(big.Float).SetInt(new(big.Int).Add(reserveIn, amountIn))
+	impact := new(big.Float).Quo(new(big.Float).SetInt(amountIn), denominator)
+	impactf, _ := impact.Float64()
+	return impactf * 100
+}
+
+// MaxAmountInForImpact returns the largest input that keeps the price impact at or below maxImpact percent.
+func MaxAmountInForImpact(reserveIn *big.Int, maxImpact float64) *big.Int {
+	if maxImpact <= 0 || maxImpact >= 100 {
+		return new(big.Int).Set(reserveIn)
+	}
+	ratio := maxImpact / 100
+	maxIn := new(big.Float).Mul(new(big.Float).SetInt(reserveIn), big.NewFloat(ratio/(1-ratio)))
+	amount, _ := maxIn.Int(nil)
+	return amount
+}
+
+// Check validates a buy of amountInEth wei of tokenAddress against the guard limits and returns
+// the amount that may be spent. When Downsize is set and only the impact ceiling is exceeded,
+// the returned amount is reduced to fit it; otherwise the trade is rejected with an error.
+func (g *LiquidityGuard) Check(ctx context.Context, client *ethclient.Client, factoryAddress, tokenAddress, wethAddress common.Address, amountInEth *big.Int) (*big.Int, error) {
+	reserves, err := GetPairReserves(ctx, client, factoryAddress, tokenAddress, wethAddress, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pair reserves: %v", err)
+	}
+
+	// Both sides of the pool are worth the same, so the total value is twice the WETH side
+	liquidityWei := new(big.Int).Mul(reserves.ReserveWETH, big.NewInt(2))
+	liquidityETH, _ := new(big.Float).Quo(new(big.Float).SetInt(liquidityWei), big.NewFloat(1e18)).Float64()
+
+	if g.MinLiquidityETH > 0 && liquidityETH < g.MinLiquidityETH {
+		log.Printf("Rejected buy of %s: pair %s liquidity %.4f ETH below floor %.4f ETH",
+			tokenAddress.Hex(), reserves.Pair.Hex(), liquidityETH, g.MinLiquidityETH)
+		return nil, ErrInsufficientLiquidity
+	}
+
+	if g.MinLiquidityUSD > 0 {
+		if g.Prices == nil {
+			return nil, errors.New("no price source configured for USD liquidity floor")
+		}
+		ethPriceInUSD, err := GetEthereumPrice(ctx, g.Prices)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Ethereum price: %v", err)
+		}
+		liquidityUSD := liquidityETH * ethPriceInUSD
+		if liquidityUSD < g.MinLiquidityUSD {
+			log.Printf("Rejected buy of %s: pair %s liquidity $%.2f below floor $%.2f",
+				tokenAddress.Hex(), reserves.Pair.Hex(), liquidityUSD, g.MinLiquidityUSD)
+			return nil, ErrInsufficientLiquidity
+		}
+	}
+
+	impact := CalculatePriceImpact(amountInEth, reserves.ReserveWETH)
+	if g.MaxPriceImpact <= 0 || impact <= g.MaxPriceImpact {
+		return amountInEth, nil
+	}
+
+	if !g.Downsize {
+		log.Printf("Rejected buy of %s: %s wei into pair %s (reserve %s wei) has price impact %.2f%%, ceiling %.2f%%",
+			tokenAddress.Hex(), amountInEth, reserves.Pair.Hex(), reserves.ReserveWETH, impact, g.MaxPriceImpact)
+		return nil, ErrPriceImpactTooHigh
+	}
+
+	downsized := MaxAmountInForImpact(reserves.ReserveWETH, g.MaxPriceImpact)
+	log.Printf("Downsized buy of %s from %s to %s wei: price impact %.2f%% exceeds ceiling %.2f%% (pair %s reserve %s wei)",
+		tokenAddress.Hex(), amountInEth, downsized, impact, g.MaxPriceImpact, reserves.Pair.Hex(), reserves.ReserveWETH)
+
+	return downsized, nil
+}
@@ -1 +1,314 @@
 package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	database "copytrader/internal/db"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	DefaultPollInterval   = 2 * time.Second
+	DefaultReloadInterval = 30 * time.Second
+	// DefaultMaxBackfill is how many missed blocks are replayed on startup, an hour on Base.
+	DefaultMaxBackfill = 1800
+)
+
+// lastBlockSetting prefixes the setting that stores the last block processed on a chain.
+const lastBlockSetting = "listener_last_block:"
+
+// rpcBlock is the subset of eth_getBlockByNumber we need. Transactions are decoded one by one
+// so that chain specific types (such as OP stack deposits) do not fail the whole block.
+type rpcBlock struct {
+	Number       hexutil.Big       `json:"number"`
+	Hash         common.Hash       `json:"hash"`
+	ParentHash   common.Hash       `json:"parentHash"`
+	Timestamp    hexutil.Uint64    `json:"timestamp"`
+	Transactions []json.RawMessage `json:"transactions"`
+}
+
+// rpcTxSender is the sender field returned alongside each transaction.
+type rpcTxSender struct {
+	From common.Address `json:"from"`
+}
+
+func fetchBlock(ctx context.Context, client *ethclient.Client, number *big.Int) (*rpcBlock, error) {
+	var block *rpcBlock
+	err := client.Client().CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeBig(number), true)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, ethereum.NotFound
+	}
+	return block, nil
+}
+
+// Listener watches new blocks for swaps sent by tracked leaders. The watch-set is loaded
+// from the Leader table and reloaded periodically, so leaders can be added or removed
+// while the listener is running. The last block processed is stored in the database, and
+// blocks missed while the listener was down are processed on startup, up to MaxBackfill.
+type Listener struct {
+	Chain          string
+	Client         *ethclient.Client
+	DB             *database.Database
+	PollInterval   time.Duration
+	ReloadInterval time.Duration
+	MaxBackfill    uint64
+	MaxReorgDepth  uint64
+	// WSURL, when set, subscribes to new heads so blocks are processed as soon as they are
+	// announced. Polling continues alongside and covers any outage of the subscription.
+	WSURL   string
+	Signals chan *Signal
+	// Reorgs receives every reorg detected, so trades copied from dropped blocks can be checked.
+	Reorgs chan *Reorg
+
+	mu        sync.RWMutex
+	leaders   map[common.Address]database.Leader
+	lastBlock uint64
+	// emitted holds the block of every signal sent, so swaps re-included after a reorg are not
+	// copied twice, and dropped the hashes of blocks replaced by reorgs.
+	emitted map[common.Hash]uint64
+	dropped map[common.Hash]uint64
+}
+
+func NewListener(chain string, client *ethclient.Client, db *database.Database) *Listener {
+	return &Listener{
+		Chain:          chain,
+		Client:         client,
+		DB:             db,
+		PollInterval:   DefaultPollInterval,
+		ReloadInterval: DefaultReloadInterval,
+		MaxBackfill:    DefaultMaxBackfill,
+		MaxReorgDepth:  DefaultMaxReorgDepth,
+		Signals:        make(chan *Signal, 100),
+		Reorgs:         make(chan *Reorg, 10),
+		leaders:        make(map[common.Address]database.Leader),
+		emitted:        make(map[common.Hash]uint64),
+		dropped:        make(map[common.Hash]uint64),
+	}
+}
+
+// Reload replaces the watch-set with the enabled leaders stored in the database.
+func (l *Listener) Reload(ctx context.Context) error {
+	rows, err := l.DB.ListEnabledLeaders(ctx, l.Chain)
+	if err != nil {
+		return fmt.Errorf("failed to load leaders: %v", err)
+	}
+
+	leaders := make(map[common.Address]database.Leader, len(rows))
+	for _, leader := range rows {
+		leaders[common.HexToAddress(leader.Address)] = leader
+	}
+
+	l.mu.Lock()
+	for address := range leaders {
+		if _, ok := l.leaders[address]; !ok {
+			log.Printf("Watching leader %s on %s", address.Hex(), l.Chain)
+		}
+	}
+	for address := range l.leaders {
+		if _, ok := leaders[address]; !ok {
+			log.Printf("Stopped watching leader %s on %s", address.Hex(), l.Chain)
+		}
+	}
+	l.leaders = leaders
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Leader returns the tracked leader for an address, if it is in the watch-set.
+func (l *Listener) Leader(address common.Address) (database.Leader, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	leader, ok := l.leaders[address]
+	return leader, ok
+}
+
+// Run processes new blocks until ctx is cancelled, emitting a Signal for every leader swap.
+func (l *Listener) Run(ctx context.Context) error {
+	if err := l.Reload(ctx); err != nil {
+		return err
+	}
+
+	start, err := l.startBlock(ctx)
+	if err != nil {
+		return err
+	}
+	l.lastBlock = start
+
+	// A nil channel never receives, so without a WebSocket URL only the poll ticker fires
+	var heads chan *types.Header
+	if l.WSURL != "" {
+		heads = make(chan *types.Header, 1)
+		go NewSubscriptionManager(l.WSURL).SubscribeNewHead(ctx, heads)
+	}
+
+	poll := time.NewTicker(l.PollInterval)
+	defer poll.Stop()
+	reload := time.NewTicker(l.ReloadInterval)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-reload.C:
+			if err := l.Reload(ctx); err != nil {
+				log.Printf("Error reloading leaders: %v", err)
+			}
+		case <-heads:
+			if err := l.poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("Error processing %s head: %v", l.Chain, err)
+			}
+		case <-poll.C:
+			if err := l.poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("Error polling %s: %v", l.Chain, err)
+			}
+		}
+	}
+}
+
+// startBlock returns the block to resume after: the last one processed by a previous run, or
+// the current head on the first run or when more than MaxBackfill blocks were missed.
+func (l *Listener) startBlock(ctx context.Context) (uint64, error) {
+	head, err := l.Client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	value, err := l.DB.GetSetting(ctx, lastBlockSetting+l.Chain)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read last processed block: %v", err)
+	}
+	if value == "" {
+		return head, nil
+	}
+	last, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || last > head {
+		log.Printf("Ignoring invalid last processed block %q on %s", value, l.Chain)
+		return head, nil
+	}
+
+	if head-last > l.MaxBackfill {
+		log.Printf("Missed %d blocks on %s, backfilling only the last %d", head-last, l.Chain, l.MaxBackfill)
+		return head - l.MaxBackfill, nil
+	}
+	if head > last {
+		log.Printf("Backfilling %d blocks on %s from block %d", head-last, l.Chain, last+1)
+	}
+	return last, nil
+}
+
+func (l *Listener) poll(ctx context.Context) error {
+	head, err := l.Client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	for number := l.lastBlock + 1; number <= head; number++ {
+		reorg, err := l.ProcessBlock(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return err
+		}
+		if reorg != nil {
+			// Rescan from the first replaced block on the next iteration
+			number = reorg.FromBlock - 1
+			if err := l.rewind(ctx, reorg); err != nil {
+				return err
+			}
+		}
+		l.lastBlock = number
+		if err := l.DB.SetSetting(ctx, lastBlockSetting+l.Chain, strconv.FormatUint(number, 10)); err != nil {
+			log.Printf("Failed to store last processed block %d: %v", number, err)
+		}
+	}
+	return nil
+}
+
+// ProcessBlock scans one block for successful swaps sent by tracked leaders. If the block does
+// not build on the block processed before it, nothing is scanned and the reorg is returned
+// instead.
+func (l *Listener) ProcessBlock(ctx context.Context, number *big.Int) (*Reorg, error) {
+	block, err := fetchBlock(ctx, l.Client, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %s: %v", number, err)
+	}
+
+	reorg, err := l.detectReorg(ctx, number.Uint64(), block.ParentHash)
+	if err != nil || reorg != nil {
+		return reorg, err
+	}
+
+	detectedAt := time.Now()
+	for _, raw := range block.Transactions {
+		var sender rpcTxSender
+		if err := json.Unmarshal(raw, &sender); err != nil {
+			continue
+		}
+		if _, ok := l.Leader(sender.From); !ok {
+			continue
+		}
+
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalJSON(raw); err != nil {
+			log.Printf("Skipping undecodable transaction from leader %s: %v", sender.From.Hex(), err)
+			continue
+		}
+
+		if l.wasEmitted(tx.Hash()) {
+			continue
+		}
+
+		signal, err := DecodeSwap(tx, sender.From)
+		if errors.Is(err, ErrNotASwap) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error decoding swap %s: %v", tx.Hash().Hex(), err)
+			continue
+		}
+
+		// A reverted swap traded nothing, so there is nothing to copy
+		receipt, err := l.Client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get receipt of %s: %v", tx.Hash().Hex(), err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			log.Printf("Skipping reverted swap %s from leader %s", tx.Hash().Hex(), sender.From.Hex())
+			continue
+		}
+
+		signal.Chain = l.Chain
+		signal.BlockNumber = block.Number.ToInt().Uint64()
+		signal.BlockHash = block.Hash
+		signal.BlockTime = time.Unix(int64(block.Timestamp), 0)
+		signal.DetectedAt = detectedAt
+
+		log.Printf("Leader %s %s %s via %s in block %d (%s)",
+			signal.Leader.Hex(), signal.Side, signal.Token.Hex(), signal.Method, signal.BlockNumber, signal.TxHash.Hex())
+
+		select {
+		case l.Signals <- signal:
+			l.markEmitted(signal.TxHash, signal.BlockNumber)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, l.recordBlock(ctx, number.Uint64(), block.Hash)
+}
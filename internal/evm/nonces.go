@@ -0,0 +1,142 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NonceManager hands out the nonces of the wallets the router sends from, per chain.
+// Transactions sent privately or in bundles don't show up in a public node's pending nonce
+// until they are mined, so nonces are counted locally from the first one the node reports,
+// and re-synced when a send is rejected for a nonce that is too low or a bundle misses every
+// block it targeted.
+type NonceManager struct {
+	mu      sync.Mutex
+	wallets map[nonceKey]*walletNonce
+}
+
+// Bundles are watched every bundlePollInterval until the blocks they target have passed, for at
+// most bundleWatchTimeout.
+const (
+	bundlePollInterval = time.Second
+	bundleWatchTimeout = 5 * time.Minute
+)
+
+type nonceKey struct {
+	chain  string
+	wallet common.Address
+}
+
+type walletNonce struct {
+	// mu is held from taking a nonce until the transaction using it is broadcast, so sends
+	// from one wallet never race for the same nonce.
+	mu     sync.Mutex
+	next   uint64
+	synced bool
+}
+
+// NewNonceManager returns a manager that syncs each wallet from its node on first use.
+func NewNonceManager() *NonceManager {
+	return &NonceManager{wallets: make(map[nonceKey]*walletNonce)}
+}
+
+func (n *NonceManager) wallet(chain string, wallet common.Address) *walletNonce {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := nonceKey{chain, wallet}
+	w, ok := n.wallets[key]
+	if !ok {
+		w = &walletNonce{}
+		n.wallets[key] = w
+	}
+	return w
+}
+
+// Reset forgets the local nonce of a wallet, so the next send syncs from the node again. It
+// is for transactions that took a nonce but will never be mined, such as bundles no block
+// included, which would otherwise leave a gap every later transaction waits behind.
+func (n *NonceManager) Reset(chain string, wallet common.Address) {
+	w := n.wallet(chain, wallet)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.synced = false
+}
+
+// Send signs the transaction build returns for the wallet's next nonce and broadcasts it. If
+// the chain rejects the nonce as too low, the wallet is re-synced from the node and the
+// transaction is rebuilt and sent once more.
+func (n *NonceManager) Send(ctx context.Context, client *ethclient.Client, signer Signer, chainConfig *ChainConfig, broadcaster Broadcaster, build func(nonce uint64) *types.Transaction) (*types.Transaction, error) {
+	from := signer.Address()
+	w := n.wallet(chainConfig.Name, from)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		if !w.synced {
+			pending, err := client.PendingNonceAt(ctx, from)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get nonce: %v", err)
+			}
+			if pending > w.next || attempt == 0 {
+				w.next = pending
+			}
+			w.synced = true
+		}
+		nonce := w.next
+
+		signedTx, err := signer.SignTx(ctx, build(nonce), chainConfig.ChainID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign transaction: %v", err)
+		}
+
+		err = broadcaster.Broadcast(ctx, client, signedTx)
+		if err == nil {
+			w.next = nonce + 1
+			if bundles, ok := broadcaster.(*BundleBroadcaster); ok {
+				go n.watchBundle(context.WithoutCancel(ctx), client, chainConfig.Name, from, signedTx.Hash(), bundles)
+			}
+			return signedTx, nil
+		}
+		if attempt == 0 && isNonceTooLow(err) {
+			// Something else used the nonce, at least the one after it is free
+			w.next = nonce + 1
+			w.synced = false
+			continue
+		}
+		return nil, fmt.Errorf("failed to send transaction via %s: %v", broadcaster.Name(), err)
+	}
+}
+
+// watchBundle resets the wallet's nonce if a bundle is not included in any block it targeted,
+// so the next transaction reuses the nonce instead of waiting behind it forever.
+func (n *NonceManager) watchBundle(ctx context.Context, client *ethclient.Client, chain string, from common.Address, hash common.Hash, bundles *BundleBroadcaster) {
+	ctx, cancel := context.WithTimeout(ctx, bundleWatchTimeout)
+	defer cancel()
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		log.Printf("Failed to watch bundle %s: %v", hash.Hex(), err)
+		return
+	}
+	included, err := WaitForInclusion(ctx, client, hash, bundles.lastBlock(head), bundlePollInterval)
+	if err != nil {
+		log.Printf("Failed to watch bundle %s: %v", hash.Hex(), err)
+		return
+	}
+	if !included {
+		log.Printf("Bundle %s from %s was not included, resetting its nonce", hash.Hex(), from.Hex())
+		n.Reset(chain, from)
+	}
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
@@ -0,0 +1,120 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ABI for the Uniswap V2 factory `getPair` function
+const factoryABI = `[{"constant":true,"inputs":[{"name":"tokenA","type":"address"},{"name":"tokenB","type":"address"}],"name":"getPair","outputs":[{"name":"pair","type":"address"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// ABI for the Uniswap V2 pair `getReserves`, `token0`, `token1` and cumulative price functions
+const pairABI = `[{"constant":true,"inputs":[],"name":"getReserves","outputs":[{"name":"_reserve0","type":"uint112"},{"name":"_reserve1","type":"uint112"},{"name":"_blockTimestampLast","type":"uint32"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"token0","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"token1","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"price0CumulativeLast","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"price1CumulativeLast","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// ErrPairNotFound is returned when the factory has no pair for the requested tokens.
+var ErrPairNotFound = errors.New("pair not found")
+
+// PairReserves holds the reserves of a token/WETH pair, already ordered by side.
+type PairReserves struct {
+	Pair          common.Address
+	ReserveWETH   *big.Int
+	ReserveToken  *big.Int
+	LastTimestamp uint32
+}
+
+// GetPairAddress looks up the pair for two tokens on a Uniswap V2 style factory.
+func GetPairAddress(ctx context.Context, client *ethclient.Client, factoryAddress, tokenA, tokenB common.Address) (common.Address, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(factoryABI))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse factory ABI: %v", err)
+	}
+
+	data, err := parsedABI.Pack("getPair", tokenA, tokenB)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to pack getPair: %v", err)
+	}
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &factoryAddress, Data: data}, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to call getPair: %v", err)
+	}
+
+	var pair common.Address
+	if err := parsedABI.UnpackIntoInterface(&pair, "getPair", result); err != nil {
+		return common.Address{}, fmt.Errorf("failed to unpack pair address: %v", err)
+	}
+	if pair == (common.Address{}) {
+		return common.Address{}, ErrPairNotFound
+	}
+
+	return pair, nil
+}
+
+// GetPairReserves fetches the reserves of the token/WETH pair at the given block (nil for latest).
+func GetPairReserves(ctx context.Context, client *ethclient.Client, factoryAddress, tokenAddress, wethAddress common.Address, blockNumber *big.Int) (*PairReserves, error) {
+	pair, err := GetPairAddress(ctx, client, factoryAddress, tokenAddress, wethAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(pairABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pair ABI: %v", err)
+	}
+
+	callMsg := ethereum.CallMsg{To: &pair, Data: parsedABI.Methods["token0"].ID}
+	result, err := client.CallContract(ctx, callMsg, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token0: %v", err)
+	}
+
+	var token0 common.Address
+	if err := parsedABI.UnpackIntoInterface(&token0, "token0", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack token0: %v", err)
+	}
+
+	callMsg.Data = parsedABI.Methods["getReserves"].ID
+	result, err = client.CallContract(ctx, callMsg, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reserves: %v", err)
+	}
+
+	values, err := parsedABI.Unpack("getReserves", result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack reserves: %v", err)
+	}
+
+	reserve0 := values[0].(*big.Int)
+	reserve1 := values[1].(*big.Int)
+
+	reserves := &PairReserves{
+		Pair:          pair,
+		LastTimestamp: values[2].(uint32),
+	}
+	if token0 == wethAddress {
+		reserves.ReserveWETH, reserves.ReserveToken = reserve0, reserve1
+	} else {
+		reserves.ReserveWETH, reserves.ReserveToken = reserve1, reserve0
+	}
+
+	return reserves, nil
+}
+
+// GetAmountOut returns what a Uniswap V2 pair pays out for amountIn, after the 0.3% fee.
+func GetAmountOut(amountIn, reserveIn, reserveOut *big.Int) *big.Int {
+	if amountIn.Sign() <= 0 || reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return new(big.Int)
+	}
+	amountInWithFee := new(big.Int).Mul(amountIn, big.NewInt(997))
+	numerator := new(big.Int).Mul(amountInWithFee, reserveOut)
+	denominator := new(big.Int).Add(new(big.Int).Mul(reserveIn, big.NewInt(1000)), amountInWithFee)
+	return numerator.Div(numerator, denominator)
+}
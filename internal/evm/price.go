@@ -0,0 +1,258 @@
+package evm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ABI for the Chainlink aggregator `latestRoundData` and `decimals` functions
+const aggregatorABI = `[{"inputs":[],"name":"latestRoundData","outputs":[{"name":"roundId","type":"uint80"},{"name":"answer","type":"int256"},{"name":"startedAt","type":"uint256"},{"name":"updatedAt","type":"uint256"},{"name":"answeredInRound","type":"uint80"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"}]`
+
+const coinGeckoETHPriceURL = "https://api.coingecko.com/api/v3/simple/price?ids=ethereum&vs_currencies=usd"
+
+var (
+	// ErrStalePrice is returned when a price source has not updated within its allowed age.
+	ErrStalePrice = errors.New("price is stale")
+	// ErrNoPriceSource is returned when every source in a fallback chain failed.
+	ErrNoPriceSource = errors.New("no price source available")
+)
+
+// ETHPrice is the USD price of one ETH as reported by a PriceSource.
+type ETHPrice struct {
+	USD       *big.Rat
+	UpdatedAt time.Time
+	Source    string
+}
+
+// Float64 returns the price as a float64, for display and coarse comparisons.
+func (p *ETHPrice) Float64() float64 {
+	f, _ := p.USD.Float64()
+	return f
+}
+
+// PriceSource provides the current ETH/USD price.
+type PriceSource interface {
+	Name() string
+	ETHPriceUSD(ctx context.Context) (*ETHPrice, error)
+}
+
+// checkAge rejects prices older than maxAge; a zero maxAge disables the check.
+func checkAge(source string, updatedAt time.Time, maxAge time.Duration) error {
+	if maxAge <= 0 {
+		return nil
+	}
+	if age := time.Since(updatedAt); age > maxAge {
+		return fmt.Errorf("%s: %w (updated %s ago, max %s)", source, ErrStalePrice, age.Round(time.Second), maxAge)
+	}
+	return nil
+}
+
+// ChainlinkPriceSource reads ETH/USD from a Chainlink aggregator such as Base's ETH/USD feed.
+type ChainlinkPriceSource struct {
+	Client     *ethclient.Client
+	Aggregator common.Address
+	MaxAge     time.Duration
+}
+
+func (c *ChainlinkPriceSource) Name() string {
+	return "chainlink"
+}
+
+func (c *ChainlinkPriceSource) ETHPriceUSD(ctx context.Context) (*ETHPrice, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(aggregatorABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse aggregator ABI: %v", err)
+	}
+
+	callMsg := ethereum.CallMsg{To: &c.Aggregator, Data: parsedABI.Methods["decimals"].ID}
+	result, err := c.Client.CallContract(ctx, callMsg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed decimals: %v", err)
+	}
+
+	var decimals uint8
+	if err := parsedABI.UnpackIntoInterface(&decimals, "decimals", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack feed decimals: %v", err)
+	}
+
+	callMsg.Data = parsedABI.Methods["latestRoundData"].ID
+	result, err = c.Client.CallContract(ctx, callMsg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest round: %v", err)
+	}
+
+	values, err := parsedABI.Unpack("latestRoundData", result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack latest round: %v", err)
+	}
+
+	roundID := values[0].(*big.Int)
+	answer := values[1].(*big.Int)
+	updatedAt := time.Unix(values[3].(*big.Int).Int64(), 0)
+	answeredInRound := values[4].(*big.Int)
+
+	if answer.Sign() <= 0 {
+		return nil, fmt.Errorf("chainlink: invalid answer %s", answer)
+	}
+	if answeredInRound.Cmp(roundID) < 0 {
+		return nil, fmt.Errorf("chainlink: %w (answered in round %s, latest %s)", ErrStalePrice, answeredInRound, roundID)
+	}
+	if err := checkAge(c.Name(), updatedAt, c.MaxAge); err != nil {
+		return nil, err
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return &ETHPrice{
+		USD:       new(big.Rat).SetFrac(answer, scale),
+		UpdatedAt: updatedAt,
+		Source:    c.Name(),
+	}, nil
+}
+
+// PoolPriceSource derives ETH/USD from the reserves of a WETH/USDC Uniswap V2 pair. The
+// reserves are always current, so the price is as old as the block they are read at; MaxAge
+// catches a node that has stopped following the chain.
+type PoolPriceSource struct {
+	Client       *ethclient.Client
+	Factory      common.Address
+	WETH         common.Address
+	USDC         common.Address
+	USDCDecimals uint8
+	MaxAge       time.Duration
+}
+
+func (p *PoolPriceSource) Name() string {
+	return "pool"
+}
+
+func (p *PoolPriceSource) ETHPriceUSD(ctx context.Context) (*ETHPrice, error) {
+	// The pair's own timestamp is its last trade, which says nothing about how current its
+	// reserves are, so date them by the block they are read at
+	head, err := p.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("pool: failed to get latest block: %v", err)
+	}
+	reserves, err := GetPairReserves(ctx, p.Client, p.Factory, p.USDC, p.WETH, head.Number)
+	if err != nil {
+		return nil, fmt.Errorf("pool: failed to get WETH/USDC reserves: %v", err)
+	}
+	if reserves.ReserveWETH.Sign() == 0 {
+		return nil, errors.New("pool: WETH/USDC pair has no liquidity")
+	}
+
+	updatedAt := time.Unix(int64(head.Time), 0)
+	if err := checkAge(p.Name(), updatedAt, p.MaxAge); err != nil {
+		return nil, err
+	}
+
+	// (reserveUSDC / 10^usdcDecimals) / (reserveWETH / 10^18)
+	usdcScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.USDCDecimals)), nil)
+	wethScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	price := new(big.Rat).SetFrac(
+		new(big.Int).Mul(reserves.ReserveToken, wethScale),
+		new(big.Int).Mul(reserves.ReserveWETH, usdcScale),
+	)
+
+	return &ETHPrice{
+		USD:       price,
+		UpdatedAt: updatedAt,
+		Source:    p.Name(),
+	}, nil
+}
+
+// CoinGeckoPriceSource fetches ETH/USD from the CoinGecko simple price API.
+type CoinGeckoPriceSource struct {
+	HTTPClient *http.Client
+	URL        string
+}
+
+func (c *CoinGeckoPriceSource) Name() string {
+	return "coingecko"
+}
+
+func (c *CoinGeckoPriceSource) ETHPriceUSD(ctx context.Context) (*ETHPrice, error) {
+	type PriceResponse struct {
+		Ethereum struct {
+			Usd json.Number `json:"usd"`
+		} `json:"ethereum"`
+	}
+
+	url := c.URL
+	if url == "" {
+		url = coinGeckoETHPriceURL
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko: unexpected status %s", resp.Status)
+	}
+
+	var priceResp PriceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&priceResp); err != nil {
+		return nil, err
+	}
+
+	price, ok := new(big.Rat).SetString(priceResp.Ethereum.Usd.String())
+	if !ok || price.Sign() <= 0 {
+		return nil, fmt.Errorf("coingecko: invalid price %q", priceResp.Ethereum.Usd)
+	}
+
+	return &ETHPrice{
+		USD:       price,
+		UpdatedAt: time.Now(),
+		Source:    c.Name(),
+	}, nil
+}
+
+// FallbackPriceSource tries each source in order and returns the first usable price.
+type FallbackPriceSource struct {
+	Sources []PriceSource
+}
+
+func (f *FallbackPriceSource) Name() string {
+	names := make([]string, len(f.Sources))
+	for i, source := range f.Sources {
+		names[i] = source.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *FallbackPriceSource) ETHPriceUSD(ctx context.Context) (*ETHPrice, error) {
+	for _, source := range f.Sources {
+		price, err := source.ETHPriceUSD(ctx)
+		if err == nil {
+			return price, nil
+		}
+		log.Printf("Price source %s failed: %v", source.Name(), err)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, ErrNoPriceSource
+}
@@ -0,0 +1,335 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ABI for the Uniswap V3 factory `getPool` function
+const factoryV3ABI = `[{"inputs":[{"name":"tokenA","type":"address"},{"name":"tokenB","type":"address"},{"name":"fee","type":"uint24"}],"name":"getPool","outputs":[{"name":"pool","type":"address"}],"stateMutability":"view","type":"function"}]`
+
+// ABI for the Uniswap V3 pool `slot0` and `observe` functions
+const poolV3ABI = `[{"inputs":[],"name":"slot0","outputs":[{"name":"sqrtPriceX96","type":"uint160"},{"name":"tick","type":"int24"},{"name":"observationIndex","type":"uint16"},{"name":"observationCardinality","type":"uint16"},{"name":"observationCardinalityNext","type":"uint16"},{"name":"feeProtocol","type":"uint8"},{"name":"unlocked","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"name":"secondsAgos","type":"uint32[]"}],"name":"observe","outputs":[{"name":"tickCumulatives","type":"int56[]"},{"name":"secondsPerLiquidityCumulativeX128s","type":"uint160[]"}],"stateMutability":"view","type":"function"}]`
+
+// V3FeeTiers are the Uniswap V3 fee tiers searched for a WETH pool, in order of preference.
+var V3FeeTiers = []int64{3000, 500, 10000, 100}
+
+// q112 is the fixed point scale of Uniswap V2 cumulative prices (UQ112x112).
+var q112 = new(big.Int).Lsh(big.NewInt(1), 112)
+
+// q192 is the square of the Uniswap V3 sqrtPriceX96 scale.
+var q192 = new(big.Int).Lsh(big.NewInt(1), 192)
+
+// TokenPricer derives token prices from on-chain pool state, with decimals applied.
+// Prices are exact rationals expressed as ETH (or USD) per whole token.
+type TokenPricer struct {
+	Client    *ethclient.Client
+	Factory   common.Address
+	FactoryV3 common.Address
+	WETH      common.Address
+	Tokens    *TokenRegistry
+	Prices    PriceSource
+}
+
+// decimals resolves a token's decimals through the registry when one is configured.
+func (p *TokenPricer) decimals(ctx context.Context, tokenAddress common.Address) (uint8, error) {
+	if p.Tokens != nil {
+		token, err := p.Tokens.Get(ctx, tokenAddress)
+		if err != nil {
+			return 0, err
+		}
+		return token.Decimals, nil
+	}
+	return FetchTokenDecimals(ctx, p.Client, tokenAddress)
+}
+
+// toETHPerToken converts a raw price (WETH base units per token base unit) into ETH per whole token.
+func toETHPerToken(raw *big.Rat, decimals uint8) *big.Rat {
+	tokenScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	wethScale := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	return new(big.Rat).Mul(raw, new(big.Rat).SetFrac(tokenScale, wethScale))
+}
+
+// rawPriceOfToken orients a token1-per-token0 raw price so it reads as WETH per token.
+func (p *TokenPricer) rawPriceOfToken(tokenAddress common.Address, token1PerToken0 *big.Rat) *big.Rat {
+	if isToken0(tokenAddress, p.WETH) {
+		return token1PerToken0
+	}
+	if token1PerToken0.Sign() == 0 {
+		return token1PerToken0
+	}
+	return new(big.Rat).Inv(token1PerToken0)
+}
+
+// isToken0 reports whether a sorts before b, which makes it token0 of their pool.
+func isToken0(a, b common.Address) bool {
+	return bytes.Compare(a.Bytes(), b.Bytes()) < 0
+}
+
+// SpotPriceETH returns the token's current price in ETH from its V2 WETH pair, falling back to a V3 pool.
+func (p *TokenPricer) SpotPriceETH(ctx context.Context, tokenAddress common.Address) (*big.Rat, error) {
+	decimals, err := p.decimals(ctx, tokenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token decimals: %v", err)
+	}
+
+	reserves, err := GetPairReserves(ctx, p.Client, p.Factory, tokenAddress, p.WETH, nil)
+	if err == nil && reserves.ReserveToken.Sign() > 0 {
+		raw := new(big.Rat).SetFrac(reserves.ReserveWETH, reserves.ReserveToken)
+		return toETHPerToken(raw, decimals), nil
+	}
+	if err != nil && !errors.Is(err, ErrPairNotFound) {
+		return nil, err
+	}
+
+	if p.FactoryV3 == (common.Address{}) {
+		return nil, ErrPairNotFound
+	}
+
+	pool, err := p.findV3Pool(ctx, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := p.v3SpotPrice(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return toETHPerToken(p.rawPriceOfToken(tokenAddress, raw), decimals), nil
+}
+
+// SpotPriceUSD returns the token's current price in USD.
+func (p *TokenPricer) SpotPriceUSD(ctx context.Context, tokenAddress common.Address) (*big.Rat, error) {
+	priceETH, err := p.SpotPriceETH(ctx, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+	return p.toUSD(ctx, priceETH)
+}
+
+// TWAPPriceETH returns the token's time-weighted average price in ETH over the last `blocks` blocks,
+// from the V2 pair's cumulative prices or, without a V2 pair, the V3 pool's tick observations.
+func (p *TokenPricer) TWAPPriceETH(ctx context.Context, tokenAddress common.Address, blocks uint64) (*big.Rat, error) {
+	decimals, err := p.decimals(ctx, tokenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token decimals: %v", err)
+	}
+
+	latest, err := p.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest header: %v", err)
+	}
+	if latest.Number.Uint64() < blocks || blocks == 0 {
+		return nil, fmt.Errorf("invalid TWAP window of %d blocks", blocks)
+	}
+	past, err := p.Client.HeaderByNumber(ctx, new(big.Int).Sub(latest.Number, new(big.Int).SetUint64(blocks)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get past header: %v", err)
+	}
+
+	pair, err := GetPairAddress(ctx, p.Client, p.Factory, tokenAddress, p.WETH)
+	if err == nil {
+		raw, err := p.v2TWAP(ctx, pair, tokenAddress, past.Number, past.Time, latest.Number, latest.Time)
+		if err != nil {
+			return nil, err
+		}
+		return toETHPerToken(raw, decimals), nil
+	}
+	if !errors.Is(err, ErrPairNotFound) || p.FactoryV3 == (common.Address{}) {
+		return nil, err
+	}
+
+	pool, err := p.findV3Pool(ctx, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := p.v3TWAP(ctx, pool, uint32(latest.Time-past.Time))
+	if err != nil {
+		return nil, err
+	}
+
+	return toETHPerToken(p.rawPriceOfToken(tokenAddress, raw), decimals), nil
+}
+
+// TWAPPriceUSD returns the token's time-weighted average price over the last `blocks` blocks in USD.
+func (p *TokenPricer) TWAPPriceUSD(ctx context.Context, tokenAddress common.Address, blocks uint64) (*big.Rat, error) {
+	priceETH, err := p.TWAPPriceETH(ctx, tokenAddress, blocks)
+	if err != nil {
+		return nil, err
+	}
+	return p.toUSD(ctx, priceETH)
+}
+
+func (p *TokenPricer) toUSD(ctx context.Context, priceETH *big.Rat) (*big.Rat, error) {
+	if p.Prices == nil {
+		return nil, errors.New("no ETH/USD price source configured")
+	}
+	ethPrice, err := p.Prices.ETHPriceUSD(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Ethereum price: %v", err)
+	}
+	return new(big.Rat).Mul(priceETH, ethPrice.USD), nil
+}
+
+// v2CumulativePrice returns the pair's counterfactual cumulative prices at the given block,
+// bringing the last stored values forward to blockTime as the pair contract would.
+func v2CumulativePrice(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, pair common.Address, blockNumber *big.Int, blockTime uint64) (*big.Int, *big.Int, error) {
+	reserves, err := callView(ctx, client, parsedABI, pair, blockNumber, "getReserves")
+	if err != nil {
+		return nil, nil, err
+	}
+	price0, err := callView(ctx, client, parsedABI, pair, blockNumber, "price0CumulativeLast")
+	if err != nil {
+		return nil, nil, err
+	}
+	price1, err := callView(ctx, client, parsedABI, pair, blockNumber, "price1CumulativeLast")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reserve0 := reserves[0].(*big.Int)
+	reserve1 := reserves[1].(*big.Int)
+	timestampLast := uint64(reserves[2].(uint32))
+	cumulative0 := new(big.Int).Set(price0[0].(*big.Int))
+	cumulative1 := new(big.Int).Set(price1[0].(*big.Int))
+
+	if elapsed := blockTime - timestampLast; blockTime > timestampLast && reserve0.Sign() > 0 && reserve1.Sign() > 0 {
+		elapsedInt := new(big.Int).SetUint64(elapsed)
+		price0 := new(big.Int).Div(new(big.Int).Mul(reserve1, q112), reserve0)
+		price1 := new(big.Int).Div(new(big.Int).Mul(reserve0, q112), reserve1)
+		cumulative0.Add(cumulative0, new(big.Int).Mul(price0, elapsedInt))
+		cumulative1.Add(cumulative1, new(big.Int).Mul(price1, elapsedInt))
+	}
+
+	return cumulative0, cumulative1, nil
+}
+
+// v2TWAP returns the raw WETH-per-token TWAP of a V2 pair between two blocks.
+func (p *TokenPricer) v2TWAP(ctx context.Context, pair, tokenAddress common.Address, startBlock *big.Int, startTime uint64, endBlock *big.Int, endTime uint64) (*big.Rat, error) {
+	if endTime <= startTime {
+		return nil, errors.New("TWAP window has no elapsed time")
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(pairABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pair ABI: %v", err)
+	}
+
+	start0, start1, err := v2CumulativePrice(ctx, p.Client, parsedABI, pair, startBlock, startTime)
+	if err != nil {
+		return nil, err
+	}
+	end0, end1, err := v2CumulativePrice(ctx, p.Client, parsedABI, pair, endBlock, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// price0 is token1 per token0, so pick the side quoted in WETH
+	start, end := start1, end1
+	if isToken0(tokenAddress, p.WETH) {
+		start, end = start0, end0
+	}
+
+	// Cumulative prices are uint256 and wrap on overflow, mirroring the pair contract
+	delta := new(big.Int).Sub(end, start)
+	if delta.Sign() < 0 {
+		delta.Add(delta, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+
+	elapsed := new(big.Int).SetUint64(endTime - startTime)
+	return new(big.Rat).SetFrac(delta, new(big.Int).Mul(q112, elapsed)), nil
+}
+
+// findV3Pool returns the first V3 WETH pool for the token across V3FeeTiers.
+func (p *TokenPricer) findV3Pool(ctx context.Context, tokenAddress common.Address) (common.Address, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(factoryV3ABI))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse V3 factory ABI: %v", err)
+	}
+
+	for _, fee := range V3FeeTiers {
+		values, err := callView(ctx, p.Client, parsedABI, p.FactoryV3, nil, "getPool", tokenAddress, p.WETH, big.NewInt(fee))
+		if err != nil {
+			return common.Address{}, err
+		}
+		if pool := values[0].(common.Address); pool != (common.Address{}) {
+			return pool, nil
+		}
+	}
+
+	return common.Address{}, ErrPairNotFound
+}
+
+// v3SpotPrice returns the raw token1-per-token0 price of a V3 pool from slot0.
+func (p *TokenPricer) v3SpotPrice(ctx context.Context, pool common.Address) (*big.Rat, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(poolV3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse V3 pool ABI: %v", err)
+	}
+
+	values, err := callView(ctx, p.Client, parsedABI, pool, nil, "slot0")
+	if err != nil {
+		return nil, err
+	}
+
+	sqrtPriceX96 := values[0].(*big.Int)
+	return new(big.Rat).SetFrac(new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96), q192), nil
+}
+
+// v3TWAP returns the raw token1-per-token0 price at the pool's average tick over the last secondsAgo seconds.
+func (p *TokenPricer) v3TWAP(ctx context.Context, pool common.Address, secondsAgo uint32) (*big.Rat, error) {
+	if secondsAgo == 0 {
+		return nil, errors.New("TWAP window has no elapsed time")
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(poolV3ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse V3 pool ABI: %v", err)
+	}
+
+	values, err := callView(ctx, p.Client, parsedABI, pool, nil, "observe", []uint32{secondsAgo, 0})
+	if err != nil {
+		return nil, err
+	}
+
+	tickCumulatives := values[0].([]*big.Int)
+	averageTick := new(big.Int).Sub(tickCumulatives[1], tickCumulatives[0])
+	// Round towards negative infinity like the Uniswap OracleLibrary
+	averageTick.Div(averageTick, big.NewInt(int64(secondsAgo)))
+
+	return tickToPrice(averageTick.Int64()), nil
+}
+
+// tickToPrice returns 1.0001^tick as a rational, evaluated at 256 bits of precision.
+func tickToPrice(tick int64) *big.Rat {
+	base := new(big.Float).SetPrec(256).SetRat(big.NewRat(10001, 10000))
+	result := new(big.Float).SetPrec(256).SetInt64(1)
+
+	exponent := tick
+	if exponent < 0 {
+		exponent = -exponent
+	}
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+		exponent >>= 1
+	}
+	if tick < 0 {
+		result.Quo(new(big.Float).SetPrec(256).SetInt64(1), result)
+	}
+
+	price, _ := result.Rat(nil)
+	return price
+}
@@ -0,0 +1,158 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	database "copytrader/internal/db"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DefaultMaxReorgDepth is how far back the listener looks for the common ancestor of a reorg.
+// Processed block hashes are kept for twice as long.
+const DefaultMaxReorgDepth = 64
+
+// Reorg is a chain reorganisation seen by the listener.
+type Reorg struct {
+	Chain string
+	// FromBlock is the first block that was replaced and ToBlock the last one processed before
+	// the reorg was noticed.
+	FromBlock uint64
+	ToBlock   uint64
+	// Dropped are the hashes of the replaced blocks.
+	Dropped []common.Hash
+}
+
+// IsReorged reports whether a block was replaced by a reorg, so signals from it still waiting
+// to be copied can be discarded.
+func (l *Listener) IsReorged(blockHash common.Hash) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.dropped[blockHash]
+	return ok
+}
+
+func (l *Listener) wasEmitted(txHash common.Hash) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.emitted[txHash]
+	return ok
+}
+
+func (l *Listener) markEmitted(txHash common.Hash, block uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.emitted[txHash] = block
+}
+
+// detectReorg checks that a block's parent is the block processed before it. If not, it walks
+// back to the last block that is still canonical and returns the reorg above it.
+func (l *Listener) detectReorg(ctx context.Context, number uint64, parentHash common.Hash) (*Reorg, error) {
+	if number == 0 {
+		return nil, nil
+	}
+	stored, err := l.DB.GetProcessedBlockHash(ctx, l.Chain, number-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block %d hash: %v", number-1, err)
+	}
+	if stored == "" || common.HexToHash(stored) == parentHash {
+		return nil, nil
+	}
+
+	ancestor := uint64(0)
+	if number-1 > l.MaxReorgDepth {
+		ancestor = number - 1 - l.MaxReorgDepth
+	}
+	for n := number - 1; n > ancestor+1; {
+		n--
+		stored, err := l.DB.GetProcessedBlockHash(ctx, l.Chain, n)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block %d hash: %v", n, err)
+		}
+		canonical, err := l.canonicalHash(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		if stored == "" || common.HexToHash(stored) == canonical {
+			ancestor = n
+			break
+		}
+	}
+
+	blocks, err := l.DB.ListProcessedBlocksFrom(ctx, l.Chain, ancestor+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load processed blocks: %v", err)
+	}
+	reorg := &Reorg{Chain: l.Chain, FromBlock: ancestor + 1, ToBlock: number - 1}
+	for _, block := range blocks {
+		if block.Number < number {
+			reorg.Dropped = append(reorg.Dropped, common.HexToHash(block.Hash))
+		}
+	}
+	return reorg, nil
+}
+
+// rewind forgets the blocks replaced by a reorg and reports it on Reorgs.
+func (l *Listener) rewind(ctx context.Context, reorg *Reorg) error {
+	log.Printf("Reorg on %s replaced blocks %d-%d, rescanning", reorg.Chain, reorg.FromBlock, reorg.ToBlock)
+
+	l.mu.Lock()
+	for i, hash := range reorg.Dropped {
+		l.dropped[hash] = reorg.FromBlock + uint64(i)
+	}
+	l.mu.Unlock()
+
+	select {
+	case l.Reorgs <- reorg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recordBlock stores a processed block's hash and forgets what is too old to be reorged.
+func (l *Listener) recordBlock(ctx context.Context, number uint64, hash common.Hash) error {
+	err := l.DB.SaveProcessedBlock(ctx, database.ProcessedBlock{Chain: l.Chain, Number: number, Hash: hash.Hex()})
+	if err != nil {
+		return fmt.Errorf("failed to store block %d hash: %v", number, err)
+	}
+
+	keep := 2 * l.MaxReorgDepth
+	if number <= keep {
+		return nil
+	}
+	if err := l.DB.PruneProcessedBlocks(ctx, l.Chain, number-keep); err != nil {
+		log.Printf("Failed to prune processed blocks: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for txHash, block := range l.emitted {
+		if block < number-keep {
+			delete(l.emitted, txHash)
+		}
+	}
+	for blockHash, block := range l.dropped {
+		if block < number-keep {
+			delete(l.dropped, blockHash)
+		}
+	}
+	return nil
+}
+
+// canonicalHash returns the hash of the canonical block at a height.
+func (l *Listener) canonicalHash(ctx context.Context, number uint64) (common.Hash, error) {
+	var block *rpcBlock
+	err := l.Client.Client().CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeBig(new(big.Int).SetUint64(number)), false)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get block %d: %v", number, err)
+	}
+	if block == nil {
+		return common.Hash{}, fmt.Errorf("block %d not found", number)
+	}
+	return block.Hash, nil
+}
@@ -0,0 +1,192 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ABI for the `owner` and `getOwner` functions of Ownable style contracts
+const ownableABI = `[{"constant":true,"inputs":[],"name":"owner","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"getOwner","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// Admin capabilities a token owner may hold over holders.
+const (
+	CapabilityMint      = "mint"
+	CapabilityBlacklist = "blacklist"
+	CapabilityPause     = "pause"
+	CapabilityFees      = "fees"
+	CapabilityLimits    = "limits"
+	CapabilityUpgrade   = "upgrade"
+)
+
+// adminFunction is an admin function signature we look for in token bytecode.
+type adminFunction struct {
+	Capability string
+	Signature  string
+}
+
+// adminFunctions are common admin signatures seen in launchpad and scam tokens.
+var adminFunctions = []adminFunction{
+	{CapabilityMint, "mint(address,uint256)"},
+	{CapabilityMint, "mint(uint256)"},
+	{CapabilityMint, "mintTo(address,uint256)"},
+	{CapabilityBlacklist, "blacklist(address)"},
+	{CapabilityBlacklist, "addBlacklist(address)"},
+	{CapabilityBlacklist, "addToBlacklist(address)"},
+	{CapabilityBlacklist, "setBlacklist(address,bool)"},
+	{CapabilityBlacklist, "setBot(address,bool)"},
+	{CapabilityBlacklist, "setBots(address[],bool)"},
+	{CapabilityBlacklist, "addBots(address[])"},
+	{CapabilityPause, "pause()"},
+	{CapabilityPause, "setPaused(bool)"},
+	{CapabilityPause, "setTrading(bool)"},
+	{CapabilityPause, "setTradingEnabled(bool)"},
+	{CapabilityFees, "setFee(uint256)"},
+	{CapabilityFees, "setFees(uint256,uint256)"},
+	{CapabilityFees, "setTaxes(uint256,uint256)"},
+	{CapabilityFees, "setBuyFee(uint256)"},
+	{CapabilityFees, "setSellFee(uint256)"},
+	{CapabilityFees, "updateFees(uint256,uint256)"},
+	{CapabilityFees, "updateBuyFees(uint256,uint256,uint256)"},
+	{CapabilityFees, "updateSellFees(uint256,uint256,uint256)"},
+	{CapabilityFees, "setTaxFeePercent(uint256)"},
+	{CapabilityLimits, "setMaxTxAmount(uint256)"},
+	{CapabilityLimits, "setMaxWalletSize(uint256)"},
+	{CapabilityLimits, "updateMaxTxnAmount(uint256)"},
+	{CapabilityLimits, "updateMaxWalletAmount(uint256)"},
+	{CapabilityUpgrade, "upgradeTo(address)"},
+	{CapabilityUpgrade, "upgradeToAndCall(address,bytes)"},
+}
+
+// CapabilityWeights is how much each admin capability adds to a token's risk score.
+var CapabilityWeights = map[string]int{
+	CapabilityMint:      40,
+	CapabilityBlacklist: 30,
+	CapabilityPause:     30,
+	CapabilityFees:      20,
+	CapabilityLimits:    10,
+	CapabilityUpgrade:   30,
+}
+
+// renouncedDivisor scales down the score of tokens whose ownership is renounced. The
+// functions may still be reachable through other roles, so the risk is not zero.
+const renouncedDivisor = 4
+
+// deadAddress is the conventional burn address ownership is sometimes transferred to.
+var deadAddress = common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+
+// RiskReport describes the admin powers left over a token and the resulting risk score.
+type RiskReport struct {
+	Token common.Address
+	// HasOwner is false when the token exposes neither owner() nor getOwner().
+	HasOwner     bool
+	Owner        common.Address
+	Renounced    bool
+	Capabilities []string
+	Score        int
+}
+
+// AnalyseTokenRisk reads the token owner, probes its bytecode for admin function selectors and
+// scores what the owner can still do to holders.
+func AnalyseTokenRisk(ctx context.Context, client *ethclient.Client, token common.Address) (*RiskReport, error) {
+	code, err := client.CodeAt(ctx, token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code: %v", err)
+	}
+	if len(code) == 0 {
+		return nil, ErrNoContract
+	}
+
+	report := &RiskReport{Token: token}
+
+	parsedABI, err := abi.JSON(strings.NewReader(ownableABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ownable ABI: %v", err)
+	}
+	for _, method := range []string{"owner", "getOwner"} {
+		values, err := callView(ctx, client, parsedABI, token, nil, method)
+		if err != nil || len(values) == 0 {
+			continue
+		}
+		report.HasOwner = true
+		report.Owner = values[0].(common.Address)
+		report.Renounced = report.Owner == (common.Address{}) || report.Owner == deadAddress
+		break
+	}
+
+	selectors := pushedSelectors(code)
+	found := make(map[string]bool)
+	for _, function := range adminFunctions {
+		if selectors[selectorOf(function.Signature)] {
+			found[function.Capability] = true
+		}
+	}
+	for capability := range found {
+		report.Capabilities = append(report.Capabilities, capability)
+		report.Score += CapabilityWeights[capability]
+	}
+	sort.Strings(report.Capabilities)
+
+	if report.Renounced {
+		report.Score /= renouncedDivisor
+	}
+
+	return report, nil
+}
+
+func selectorOf(signature string) [4]byte {
+	var selector [4]byte
+	copy(selector[:], crypto.Keccak256([]byte(signature))[:4])
+	return selector
+}
+
+// pushedSelectors returns every 4 byte value pushed with PUSH4, which is how the Solidity
+// dispatcher compares function selectors. Push data is skipped so it is not read as opcodes.
+func pushedSelectors(code []byte) map[[4]byte]bool {
+	const (
+		push1  = 0x60
+		push4  = 0x63
+		push32 = 0x7f
+	)
+
+	selectors := make(map[[4]byte]bool)
+	for i := 0; i < len(code); i++ {
+		op := code[i]
+		if op < push1 || op > push32 {
+			continue
+		}
+		size := int(op-push1) + 1
+		if op == push4 && i+4 < len(code) {
+			var selector [4]byte
+			copy(selector[:], code[i+1:i+5])
+			selectors[selector] = true
+		}
+		i += size
+	}
+
+	return selectors
+}
+
+// String summarises the report for logs.
+func (r *RiskReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "risk score %d", r.Score)
+	switch {
+	case !r.HasOwner:
+		b.WriteString(", no owner()")
+	case r.Renounced:
+		b.WriteString(", ownership renounced")
+	default:
+		fmt.Fprintf(&b, ", owned by %s", r.Owner.Hex())
+	}
+	if len(r.Capabilities) > 0 {
+		fmt.Fprintf(&b, ", can %s", strings.Join(r.Capabilities, ","))
+	}
+	return b.String()
+}
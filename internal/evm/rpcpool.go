@@ -0,0 +1,392 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Defaults for RPC pools.
+const (
+	DefaultHealthInterval = 15 * time.Second
+	// DefaultMaxLag is how many blocks an endpoint may trail the best head and still serve reads.
+	DefaultMaxLag = 3
+)
+
+// ErrNoEndpoint is returned when every endpoint of a pool failed a request.
+var ErrNoEndpoint = errors.New("no RPC endpoint available")
+
+type rpcEndpoint struct {
+	url string
+
+	mu sync.Mutex
+	// failing is set by the last request that errored and cleared by the next that succeeds.
+	failing bool
+	// lagging is set by health checks while the endpoint trails the best head, whatever
+	// requests in between return.
+	lagging bool
+	// latency is a moving average of request round trips.
+	latency time.Duration
+	head    uint64
+}
+
+func (e *rpcEndpoint) observe(took time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.failing = true
+		return
+	}
+	e.failing = false
+	if e.latency == 0 {
+		e.latency = took
+	} else {
+		e.latency = (e.latency*4 + took) / 5
+	}
+}
+
+func (e *rpcEndpoint) state() (bool, time.Duration, uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.failing && !e.lagging, e.latency, e.head
+}
+
+// RPCPool spreads JSON-RPC requests over several HTTP endpoints of one chain. It is used as
+// the transport of an ethclient.Client: reads go to the fastest healthy endpoint and fail over
+// to the next on errors, while raw transactions are broadcast to every endpoint in parallel so
+// they propagate faster.
+type RPCPool struct {
+	HTTPClient     *http.Client
+	HealthInterval time.Duration
+	MaxLag         uint64
+
+	endpoints []*rpcEndpoint
+}
+
+// NewRPCPool returns a pool over the given endpoint URLs, all assumed healthy until checked.
+func NewRPCPool(urls []string) *RPCPool {
+	pool := &RPCPool{
+		HTTPClient:     &http.Client{Timeout: 30 * time.Second},
+		HealthInterval: DefaultHealthInterval,
+		MaxLag:         DefaultMaxLag,
+	}
+	for _, url := range urls {
+		pool.endpoints = append(pool.endpoints, &rpcEndpoint{url: url})
+	}
+	return pool
+}
+
+// DialRPC connects to a chain through one or more RPC endpoints, giving up on HTTP requests
+// that take longer than timeout. A single endpoint, which may be a WebSocket URL, is dialled
+// directly. Several must be HTTP endpoints and are pooled, with health checks running until
+// ctx is cancelled.
+func DialRPC(ctx context.Context, urls []string, timeout time.Duration) (*ethclient.Client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no RPC endpoints configured")
+	}
+	if len(urls) == 1 {
+		// WebSocket requests are only bounded by the contexts they are made with
+		client, err := rpc.DialOptions(ctx, urls[0], rpc.WithHTTPClient(&http.Client{Timeout: timeout}))
+		if err != nil {
+			return nil, err
+		}
+		return ethclient.NewClient(client), nil
+	}
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("pooled RPC endpoints must be HTTP, got %s", url)
+		}
+	}
+
+	pool := NewRPCPool(urls)
+	pool.HTTPClient.Timeout = timeout
+	pool.Check(ctx)
+	go pool.Run(ctx)
+
+	// The URL is a placeholder, the pool decides where each request goes
+	client, err := rpc.DialOptions(ctx, urls[0], rpc.WithHTTPClient(&http.Client{Transport: pool}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pooled RPC client: %v", err)
+	}
+	return ethclient.NewClient(client), nil
+}
+
+// SplitRPCURLs splits a comma separated list of RPC URLs.
+func SplitRPCURLs(list string) []string {
+	var urls []string
+	for _, url := range strings.Split(list, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// Run health checks the endpoints every HealthInterval until ctx is cancelled.
+func (p *RPCPool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Check(ctx)
+		}
+	}
+}
+
+// Check asks every endpoint for its head block. Endpoints that fail are unhealthy until a
+// request to them succeeds, those trailing the best head by more than MaxLag blocks until a
+// later check finds them caught up.
+func (p *RPCPool) Check(ctx context.Context) {
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+
+	var wg sync.WaitGroup
+	for _, endpoint := range p.endpoints {
+		wg.Add(1)
+		go func(endpoint *rpcEndpoint) {
+			defer wg.Done()
+			start := time.Now()
+			body, err := p.post(ctx, endpoint.url, request)
+			var head hexutil.Uint64
+			if err == nil {
+				err = decodeResult(body, &head)
+			}
+			endpoint.observe(time.Since(start), err)
+			if err != nil {
+				log.Printf("RPC endpoint %s failed its health check: %v", endpoint.url, err)
+				return
+			}
+			endpoint.mu.Lock()
+			endpoint.head = uint64(head)
+			endpoint.mu.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+
+	var best uint64
+	for _, endpoint := range p.endpoints {
+		if _, _, head := endpoint.state(); head > best {
+			best = head
+		}
+	}
+	for _, endpoint := range p.endpoints {
+		endpoint.mu.Lock()
+		lagging := endpoint.head+p.MaxLag < best
+		if lagging && !endpoint.failing {
+			log.Printf("RPC endpoint %s is %d blocks behind", endpoint.url, best-endpoint.head)
+		}
+		endpoint.lagging = lagging
+		endpoint.mu.Unlock()
+	}
+}
+
+// ranked returns the endpoints healthy first, then by latency.
+func (p *RPCPool) ranked() []*rpcEndpoint {
+	type ranking struct {
+		endpoint *rpcEndpoint
+		healthy  bool
+		latency  time.Duration
+	}
+	rankings := make([]ranking, len(p.endpoints))
+	for i, endpoint := range p.endpoints {
+		healthy, latency, _ := endpoint.state()
+		rankings[i] = ranking{endpoint, healthy, latency}
+	}
+	sort.SliceStable(rankings, func(i, j int) bool {
+		if rankings[i].healthy != rankings[j].healthy {
+			return rankings[i].healthy
+		}
+		return rankings[i].latency < rankings[j].latency
+	})
+
+	endpoints := make([]*rpcEndpoint, len(rankings))
+	for i, r := range rankings {
+		endpoints[i] = r.endpoint
+	}
+	return endpoints
+}
+
+// RoundTrip implements http.RoundTrip for the JSON-RPC requests of an rpc.Client.
+func (p *RPCPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var response []byte
+	if isBroadcast(body) {
+		response, err = p.broadcast(req.Context(), body)
+	} else {
+		response, err = p.read(req.Context(), body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(response)),
+		ContentLength: int64(len(response)),
+		Request:       req,
+	}, nil
+}
+
+// read sends a request to each endpoint in turn, fastest healthy first, until one answers.
+// JSON-RPC errors such as reverts are answers and are not retried.
+func (p *RPCPool) read(ctx context.Context, body []byte) ([]byte, error) {
+	var lastErr error
+	for _, endpoint := range p.ranked() {
+		start := time.Now()
+		response, err := p.post(ctx, endpoint.url, body)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		endpoint.observe(time.Since(start), err)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %v", ErrNoEndpoint, lastErr)
+}
+
+// broadcast sends a request to every endpoint at once and returns the first answer without a
+// JSON-RPC error, or the first answer at all if every endpoint rejected it. The rest are left
+// to finish in the background.
+func (p *RPCPool) broadcast(ctx context.Context, body []byte) ([]byte, error) {
+	type result struct {
+		response []byte
+		err      error
+	}
+	results := make(chan result, len(p.endpoints))
+	for _, endpoint := range p.endpoints {
+		go func(endpoint *rpcEndpoint) {
+			start := time.Now()
+			response, err := p.post(context.WithoutCancel(ctx), endpoint.url, body)
+			endpoint.observe(time.Since(start), err)
+			results <- result{response, err}
+		}(endpoint)
+	}
+
+	var rejected []byte
+	var lastErr error
+	for range p.endpoints {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				lastErr = r.err
+				continue
+			}
+			if !hasRPCError(r.response) {
+				return r.response, nil
+			}
+			if rejected == nil {
+				rejected = r.response
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if rejected != nil {
+		return rejected, nil
+	}
+	return nil, fmt.Errorf("%w: %v", ErrNoEndpoint, lastErr)
+}
+
+func (p *RPCPool) post(ctx context.Context, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return response, nil
+}
+
+type rpcMessage struct {
+	Method string          `json:"method,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// decodeMessages parses a single JSON-RPC message or a batch of them.
+func decodeMessages(body []byte) []rpcMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []rpcMessage
+		json.Unmarshal(body, &batch)
+		return batch
+	}
+	var message rpcMessage
+	if json.Unmarshal(body, &message) != nil {
+		return nil
+	}
+	return []rpcMessage{message}
+}
+
+// isBroadcast reports whether a request sends a signed transaction.
+func isBroadcast(body []byte) bool {
+	for _, message := range decodeMessages(body) {
+		if message.Method == "eth_sendRawTransaction" {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRPCError(body []byte) bool {
+	for _, message := range decodeMessages(body) {
+		if len(message.Error) > 0 && string(message.Error) != "null" {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeResult(body []byte, result interface{}) error {
+	messages := decodeMessages(body)
+	if len(messages) != 1 {
+		return errors.New("invalid JSON-RPC response")
+	}
+	if len(messages[0].Error) > 0 && string(messages[0].Error) != "null" {
+		return fmt.Errorf("JSON-RPC error: %s", messages[0].Error)
+	}
+	if len(messages[0].Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(messages[0].Result, result)
+}
@@ -0,0 +1,99 @@
+package evm
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Signal sides.
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// ErrNotASwap is returned for transactions that are not a recognised router swap.
+var ErrNotASwap = errors.New("transaction is not a router swap")
+
+// Signal is a leader trade detected on-chain.
+type Signal struct {
+	Chain       string
+	Leader      common.Address
+	TxHash      common.Hash
+	BlockNumber uint64
+	BlockHash   common.Hash
+	BlockTime   time.Time
+	DetectedAt  time.Time
+	Router      common.Address
+	Method      string
+	Side        string
+	Token       common.Address
+	// AmountIn is the ETH (buys) or token amount (sells) the leader spent, in base units.
+	AmountIn *big.Int
+	// AmountOutMin is the minimum the leader accepted, in base units.
+	AmountOutMin *big.Int
+}
+
+// DecodeSwap decodes a Uniswap V2 router swap into a Signal without the chain and block fields.
+func DecodeSwap(tx *types.Transaction, leader common.Address) (*Signal, error) {
+	if tx.To() == nil || len(tx.Data()) < 4 {
+		return nil, ErrNotASwap
+	}
+
+	routerABI, err := RouterMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := routerABI.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil, ErrNotASwap
+	}
+
+	args := make(map[string]interface{})
+	if err := method.Inputs.UnpackIntoMap(args, tx.Data()[4:]); err != nil {
+		return nil, err
+	}
+
+	path, _ := args["path"].([]common.Address)
+	if len(path) < 2 {
+		return nil, ErrNotASwap
+	}
+
+	signal := &Signal{
+		Leader: leader,
+		TxHash: tx.Hash(),
+		Router: *tx.To(),
+		Method: method.Name,
+	}
+
+	switch method.Name {
+	case "swapExactETHForTokens", "swapExactETHForTokensSupportingFeeOnTransferTokens":
+		signal.Side = SideBuy
+		signal.Token = path[len(path)-1]
+		signal.AmountIn = tx.Value()
+		signal.AmountOutMin = args["amountOutMin"].(*big.Int)
+	case "swapETHForExactTokens":
+		signal.Side = SideBuy
+		signal.Token = path[len(path)-1]
+		signal.AmountIn = tx.Value()
+		signal.AmountOutMin = args["amountOut"].(*big.Int)
+	case "swapExactTokensForETH", "swapExactTokensForETHSupportingFeeOnTransferTokens":
+		signal.Side = SideSell
+		signal.Token = path[0]
+		signal.AmountIn = args["amountIn"].(*big.Int)
+		signal.AmountOutMin = args["amountOutMin"].(*big.Int)
+	case "swapTokensForExactETH":
+		signal.Side = SideSell
+		signal.Token = path[0]
+		signal.AmountIn = args["amountInMax"].(*big.Int)
+		signal.AmountOutMin = args["amountOut"].(*big.Int)
+	default:
+		return nil, ErrNotASwap
+	}
+
+	return signal, nil
+}
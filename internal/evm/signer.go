@@ -0,0 +1,236 @@
+package evm
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer signs transactions for a single account, wherever its key lives.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// TypedDataSigner is implemented by signers that can also sign EIP-712 typed data, which
+// Permit2 permits need.
+type TypedDataSigner interface {
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+// KeySigner signs with a private key held in memory.
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+func (s *KeySigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	return signTypedData(s.key, data)
+}
+
+// KeystoreSigner signs with a key from the encrypted keystore, which must already be unlocked.
+type KeystoreSigner struct {
+	Keys    *KeyStore
+	Account common.Address
+}
+
+func (s *KeystoreSigner) Address() common.Address {
+	return s.Account
+}
+
+func (s *KeystoreSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := s.Keys.Key(s.Account)
+	if err != nil {
+		return nil, err
+	}
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+}
+
+func (s *KeystoreSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	key, err := s.Keys.Key(s.Account)
+	if err != nil {
+		return nil, err
+	}
+	return signTypedData(key, data)
+}
+
+// signTypedData signs the EIP-712 hash of data, with v as 27 or 28 as contracts expect.
+func signTypedData(key *ecdsa.PrivateKey, data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %v", err)
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// RemoteSigner asks an external JSON-RPC signer to sign, so the key never enters this process.
+// It speaks eth_signTransaction as implemented by Web3Signer; set Method to
+// "account_signTransaction" for Clef.
+type RemoteSigner struct {
+	URL     string
+	Account common.Address
+	Method  string
+}
+
+// remoteSignArgs mirrors the transaction object accepted by eth_signTransaction.
+type remoteSignArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to,omitempty"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     hexutil.Bytes   `json:"data"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	ChainID  *hexutil.Big    `json:"chainId"`
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.Account
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	client, err := rpc.DialContext(ctx, s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %v", err)
+	}
+	defer client.Close()
+
+	method := s.Method
+	if method == "" {
+		method = "eth_signTransaction"
+	}
+
+	args := remoteSignArgs{
+		From:     s.Account,
+		To:       tx.To(),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Data:     tx.Data(),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		ChainID:  (*hexutil.Big)(chainID),
+	}
+
+	var result json.RawMessage
+	if err := client.CallContext(ctx, &result, method, args); err != nil {
+		return nil, fmt.Errorf("remote signer rejected transaction: %v", err)
+	}
+
+	raw, err := decodeSignResult(result)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode signed transaction: %v", err)
+	}
+
+	// Never broadcast something other than what we asked to be signed: the signing hash covers
+	// every field of the transaction (nonce, gas, fees, to, value, data), the chain id is checked
+	// apart since an unprotected signature would still recover
+	signer := types.LatestSignerForChainID(chainID)
+	if signedTx.Type() != tx.Type() || signedTx.ChainId().Cmp(chainID) != 0 || signer.Hash(signedTx) != signer.Hash(tx) {
+		return nil, errors.New("remote signer returned a different transaction")
+	}
+	sender, err := types.Sender(signer, signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signer: %v", err)
+	}
+	if sender != s.Account {
+		return nil, fmt.Errorf("remote signer signed as %s, expected %s", sender.Hex(), s.Account.Hex())
+	}
+
+	return signedTx, nil
+}
+
+// SignTypedData asks the remote signer to sign EIP-712 typed data, with eth_signTypedData for
+// Web3Signer or account_signTypedData for Clef.
+func (s *RemoteSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %v", err)
+	}
+
+	client, err := rpc.DialContext(ctx, s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %v", err)
+	}
+	defer client.Close()
+
+	method := "eth_signTypedData"
+	if s.Method == "account_signTransaction" {
+		method = "account_signTypedData"
+	}
+
+	var signature hexutil.Bytes
+	if err := client.CallContext(ctx, &signature, method, s.Account, data); err != nil {
+		return nil, fmt.Errorf("remote signer rejected typed data: %v", err)
+	}
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("unexpected remote signer signature length %d", len(signature))
+	}
+
+	// Make sure the signature is over what we asked for, by the account we asked for
+	recover := bytes.Clone(signature)
+	if recover[crypto.RecoveryIDOffset] >= 27 {
+		recover[crypto.RecoveryIDOffset] -= 27
+	} else {
+		signature[crypto.RecoveryIDOffset] += 27
+	}
+	pub, err := crypto.SigToPub(hash, recover)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover signer: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != s.Account {
+		return nil, fmt.Errorf("remote signer signed as %s, expected %s", signer.Hex(), s.Account.Hex())
+	}
+	return signature, nil
+}
+
+// decodeSignResult accepts both a bare raw transaction (Web3Signer) and a {raw, tx} object (Clef).
+func decodeSignResult(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+
+	var envelope struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &envelope); err != nil || len(envelope.Raw) == 0 {
+		return nil, fmt.Errorf("unexpected remote signer response: %s", string(result))
+	}
+	return envelope.Raw, nil
+}
@@ -0,0 +1,240 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// remoteSignerStub stands in for Web3Signer or Clef: it signs whatever it is asked to with key,
+// after letting tamper rewrite the transaction and choose the signer.
+type remoteSignerStub struct {
+	key    *ecdsa.PrivateKey
+	tamper func(tx *types.LegacyTx, chainID *big.Int) types.Signer
+	calls  []string
+}
+
+func (s *remoteSignerStub) serve(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage  `json:"id"`
+			Method string           `json:"method"`
+			Params []remoteSignArgs `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Params) != 1 {
+			t.Errorf("bad signer request: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		s.calls = append(s.calls, request.Method)
+
+		args := request.Params[0]
+		tx := &types.LegacyTx{
+			Nonce:    uint64(args.Nonce),
+			GasPrice: args.GasPrice.ToInt(),
+			Gas:      uint64(args.Gas),
+			To:       args.To,
+			Value:    args.Value.ToInt(),
+			Data:     args.Data,
+		}
+		var signer types.Signer = types.LatestSignerForChainID(args.ChainID.ToInt())
+		if s.tamper != nil {
+			signer = s.tamper(tx, args.ChainID.ToInt())
+		}
+		signed, err := types.SignNewTx(s.key, signer, tx)
+		if err != nil {
+			t.Errorf("failed to sign: %v", err)
+			return
+		}
+		raw, err := signed.MarshalBinary()
+		if err != nil {
+			t.Errorf("failed to encode: %v", err)
+			return
+		}
+
+		// Clef wraps the raw transaction, Web3Signer returns it bare
+		var result any = hexutil.Bytes(raw)
+		if request.Method == "account_signTransaction" {
+			result = map[string]any{"raw": hexutil.Bytes(raw), "tx": signed}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func unsignedTestTx() *types.Transaction {
+	to := common.HexToAddress("0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24")
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    7,
+		GasPrice: big.NewInt(1_000_000_000),
+		Gas:      210_000,
+		To:       &to,
+		Value:    big.NewInt(1e15),
+		Data:     []byte{0x7f, 0xf3, 0x6a, 0xb5},
+	})
+}
+
+func TestRemoteSignerSignTx(t *testing.T) {
+	chainID := big.NewInt(8453)
+	key := newTestKey(t)
+	account := crypto.PubkeyToAddress(key.PublicKey)
+
+	for _, method := range []string{"", "account_signTransaction"} {
+		stub := &remoteSignerStub{key: key}
+		server := stub.serve(t)
+		signer := &RemoteSigner{URL: server.URL, Account: account, Method: method}
+
+		tx := unsignedTestTx()
+		signed, err := signer.SignTx(context.Background(), tx, chainID)
+		if err != nil {
+			t.Fatalf("method %q: unexpected error: %v", method, err)
+		}
+
+		want := method
+		if want == "" {
+			want = "eth_signTransaction"
+		}
+		if len(stub.calls) != 1 || stub.calls[0] != want {
+			t.Fatalf("method %q: signer called with %v", method, stub.calls)
+		}
+		if signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() || signed.GasPrice().Cmp(tx.GasPrice()) != 0 ||
+			*signed.To() != *tx.To() || signed.Value().Cmp(tx.Value()) != 0 || string(signed.Data()) != string(tx.Data()) {
+			t.Fatalf("method %q: signed transaction differs from the request", method)
+		}
+		if signed.ChainId().Cmp(chainID) != 0 {
+			t.Fatalf("method %q: chain id %s, want %s", method, signed.ChainId(), chainID)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil || sender != account {
+			t.Fatalf("method %q: sender %s (%v), want %s", method, sender.Hex(), err, account.Hex())
+		}
+	}
+}
+
+func TestRemoteSignerRejectsMismatches(t *testing.T) {
+	chainID := big.NewInt(8453)
+	key := newTestKey(t)
+	account := crypto.PubkeyToAddress(key.PublicKey)
+
+	tests := []struct {
+		name    string
+		key     *ecdsa.PrivateKey
+		tamper  func(tx *types.LegacyTx, chainID *big.Int) types.Signer
+		wantErr string
+	}{
+		{
+			name: "raised gas price",
+			tamper: func(tx *types.LegacyTx, chainID *big.Int) types.Signer {
+				tx.GasPrice = new(big.Int).Mul(tx.GasPrice, big.NewInt(100))
+				return types.LatestSignerForChainID(chainID)
+			},
+			wantErr: "different transaction",
+		},
+		{
+			name: "different nonce",
+			tamper: func(tx *types.LegacyTx, chainID *big.Int) types.Signer {
+				tx.Nonce++
+				return types.LatestSignerForChainID(chainID)
+			},
+			wantErr: "different transaction",
+		},
+		{
+			name: "different recipient",
+			tamper: func(tx *types.LegacyTx, chainID *big.Int) types.Signer {
+				to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+				tx.To = &to
+				return types.LatestSignerForChainID(chainID)
+			},
+			wantErr: "different transaction",
+		},
+		{
+			name: "different value",
+			tamper: func(tx *types.LegacyTx, chainID *big.Int) types.Signer {
+				tx.Value = big.NewInt(1e18)
+				return types.LatestSignerForChainID(chainID)
+			},
+			wantErr: "different transaction",
+		},
+		{
+			name: "other chain",
+			tamper: func(tx *types.LegacyTx, chainID *big.Int) types.Signer {
+				return types.LatestSignerForChainID(big.NewInt(1))
+			},
+			wantErr: "different transaction",
+		},
+		{
+			name: "unprotected",
+			tamper: func(tx *types.LegacyTx, chainID *big.Int) types.Signer {
+				return types.HomesteadSigner{}
+			},
+			wantErr: "different transaction",
+		},
+		{
+			name:    "wrong account",
+			key:     newTestKey(t),
+			wantErr: "remote signer signed as",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signingKey := key
+			if tt.key != nil {
+				signingKey = tt.key
+			}
+			stub := &remoteSignerStub{key: signingKey, tamper: tt.tamper}
+			server := stub.serve(t)
+			signer := &RemoteSigner{URL: server.URL, Account: account}
+
+			signed, err := signer.SignTx(context.Background(), unsignedTestTx(), chainID)
+			if err == nil {
+				t.Fatalf("expected an error, got transaction %s", signed.Hash().Hex())
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %q does not mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRemoteSignerPropagatesRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      request.ID,
+			"error":   map[string]any{"code": -32000, "message": "signing denied by policy"},
+		})
+	}))
+	defer server.Close()
+
+	signer := &RemoteSigner{URL: server.URL, Account: common.HexToAddress("0x01")}
+	_, err := signer.SignTx(context.Background(), unsignedTestTx(), big.NewInt(8453))
+	if err == nil || !strings.Contains(err.Error(), "signing denied by policy") {
+		t.Fatalf("expected the signer's rejection, got %v", err)
+	}
+}
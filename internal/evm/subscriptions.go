@@ -0,0 +1,103 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Reconnect backoff defaults for subscriptions.
+const (
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Minute
+)
+
+// SubscriptionManager keeps WebSocket subscriptions alive. When a subscription dies, from a
+// provider disconnect or otherwise, it redials with exponential backoff and resubscribes.
+type SubscriptionManager struct {
+	URL        string
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func NewSubscriptionManager(url string) *SubscriptionManager {
+	return &SubscriptionManager{
+		URL:        url,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// SubscribeNewHead sends every new head to heads until ctx is cancelled. Heads announced
+// during an outage are not replayed, so consumers should process from the last block they
+// handled up to each head they receive.
+func (m *SubscriptionManager) SubscribeNewHead(ctx context.Context, heads chan<- *types.Header) error {
+	return m.run(ctx, "new heads", func(client *ethclient.Client) error {
+		ch := make(chan *types.Header)
+		sub, err := client.SubscribeNewHead(ctx, ch)
+		if err != nil {
+			return err
+		}
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-sub.Err():
+				return err
+			case head := <-ch:
+				select {
+				case heads <- head:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	})
+}
+
+// run dials and calls subscribe until ctx is cancelled, backing off between failed attempts.
+// The backoff resets once a subscription has stayed up for MaxBackoff.
+func (m *SubscriptionManager) run(ctx context.Context, name string, subscribe func(*ethclient.Client) error) error {
+	backoff := m.MinBackoff
+	for {
+		started := time.Now()
+		err := m.session(ctx, subscribe)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(started) > m.MaxBackoff {
+			backoff = m.MinBackoff
+		}
+		log.Printf("Subscription to %s on %s dropped: %v, reconnecting in %s", name, m.URL, err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if backoff > m.MaxBackoff {
+			backoff = m.MaxBackoff
+		}
+	}
+}
+
+func (m *SubscriptionManager) session(ctx context.Context, subscribe func(*ethclient.Client) error) error {
+	client, err := ethclient.DialContext(ctx, m.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	err = subscribe(client)
+	if err == nil {
+		err = fmt.Errorf("subscription closed")
+	}
+	return err
+}
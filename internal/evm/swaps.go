@@ -2,7 +2,6 @@ package evm
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"log"
@@ -15,56 +14,79 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 const ORouterABI = `[{"constant":false,"inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}],"name":"swapExactETHForTokens","outputs":[{"name":"amounts","type":"uint256[]"}],"payable":true,"stateMutability":"payable","type":"function"}]`
 
-type ChainConfig struct {
-	Name    string
-	ChainID *big.Int
-	RPCURL  string
-}
-
 type MultiChainRouter struct {
 	Clients map[string]*ethclient.Client
 	Chains  map[string]*ChainConfig
+	Signers map[common.Address]Signer
+	// Broadcasters are the private submission routes swaps can select by name.
+	Broadcasters map[string]Broadcaster
+	// Nonces numbers every transaction the router sends, whatever route it takes.
+	Nonces *NonceManager
 }
 
-func NewMultiChainRouter(configs []*ChainConfig) (*MultiChainRouter, error) {
-	clients := make(map[string]*ethclient.Client)
+// NewMultiChainRouter connects to every configured chain, checking that each node serves the
+// chain ID it is configured with, and registers the signers it may send from. Pooled endpoints
+// are health checked until ctx is cancelled.
+func NewMultiChainRouter(ctx context.Context, configs []*ChainConfig, signers []Signer) (*MultiChainRouter, error) {
+	router := &MultiChainRouter{
+		Clients:      make(map[string]*ethclient.Client),
+		Chains:       make(map[string]*ChainConfig),
+		Signers:      make(map[common.Address]Signer),
+		Broadcasters: make(map[string]Broadcaster),
+		Nonces:       NewNonceManager(),
+	}
 	for _, config := range configs {
-		client, err := ethclient.Dial(config.RPCURL)
+		client, err := DialRPC(ctx, config.Endpoints(), config.Timeout())
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to chain %s: %v", config.Name, err)
 		}
-		clients[config.Name] = client
+		if err := verifyChainID(client, config); err != nil {
+			client.Close()
+			return nil, err
+		}
+		router.Clients[config.Name] = client
+		router.Chains[config.Name] = config
 	}
-	return &MultiChainRouter{
-		Clients: clients,
-		Chains:  make(map[string]*ChainConfig),
-	}, nil
+	for _, signer := range signers {
+		router.AddSigner(signer)
+	}
+	return router, nil
+}
+
+// AddSigner registers a signer so the router can send transactions from its address.
+func (m *MultiChainRouter) AddSigner(signer Signer) {
+	m.Signers[signer.Address()] = signer
 }
 
-func (m *MultiChainRouter) SwapETHForToken(chainName, userWalletPrivateKey string, router, wethAddr, tokenAddress common.Address, amountInEth, minTokens *big.Int) (string, error) {
-	client, ok := m.Clients[chainName]
+func (m *MultiChainRouter) signer(from common.Address) (Signer, error) {
+	signer, ok := m.Signers[from]
 	if !ok {
-		return "", fmt.Errorf("unsupported chain: %s", chainName)
-	}
-	chainConfig, exists := m.Chains[chainName]
-	if !exists {
-		return "", fmt.Errorf("no configuration found for chain: %s", chainName)
+		return nil, fmt.Errorf("no signer registered for %s", from.Hex())
 	}
+	return signer, nil
+}
 
-	privateKey, err := crypto.HexToECDSA(userWalletPrivateKey)
+// SwapETHForToken buys a token with ETH, submitting the swap through the named broadcast mode.
+func (m *MultiChainRouter) SwapETHForToken(ctx context.Context, chainName string, from common.Address, router, wethAddr, tokenAddress common.Address, amountInEth, minTokens *big.Int, broadcast string) (string, error) {
+	client, chainConfig, err := m.chain(chainName)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, chainConfig.Timeout())
+	defer cancel()
+	broadcaster, err := m.broadcaster(broadcast)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse private key: %v", err)
+		return "", err
 	}
 
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainConfig.ChainID)
+	signer, err := m.signer(from)
 	if err != nil {
-		return "", fmt.Errorf("failed to create transaction signer: %v", err)
+		return "", err
 	}
 
 	routerABI, err := abi.JSON(strings.NewReader(ORouterABI))
@@ -74,89 +96,101 @@ func (m *MultiChainRouter) SwapETHForToken(chainName, userWalletPrivateKey strin
 
 	path := []common.Address{wethAddr, tokenAddress}
 
-	data, err := routerABI.Pack("swapExactETHForTokens", minTokens, path, auth.From, big.NewInt(time.Now().Add(time.Minute*10).Unix()))
+	data, err := routerABI.Pack("swapExactETHForTokens", minTokens, path, from, big.NewInt(time.Now().Add(time.Minute*10).Unix()))
 	if err != nil {
 		return "", fmt.Errorf("failed to pack data: %v", err)
 	}
 
 	msg := ethereum.CallMsg{
+		From:  from,
 		To:    &router,
 		Value: amountInEth,
 		Data:  data,
 	}
 
-	gasLimit, err := estimateGas(client, msg)
+	gasLimit, err := estimateGas(ctx, client, msg)
 	if err != nil {
 		return "", fmt.Errorf("failed to estimate gas: %v", err)
 	}
 
-	nonce, err := client.PendingNonceAt(context.Background(), auth.From)
+	gasPrice, err := client.SuggestGasPrice(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get nonce: %v", err)
+		return "", fmt.Errorf("failed to get gas price: %v", err)
 	}
 
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	signedTx, err := m.Nonces.Send(ctx, client, signer, chainConfig, broadcaster, func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, router, amountInEth, gasLimit, gasPrice, data)
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get gas price: %v", err)
+		return "", err
 	}
 
-	tx := types.NewTransaction(nonce, router, amountInEth, gasLimit, gasPrice, data)
+	return signedTx.Hash().Hex(), nil
+}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainConfig.ChainID), privateKey)
+// ApproveToken approves spender to spend amount of a token and waits for the approval to be mined.
+func (m *MultiChainRouter) ApproveToken(ctx context.Context, chainName string, from common.Address, tokenAddress, spender common.Address, amount *big.Int) error {
+	client, err := m.Client(chainName)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %v", err)
+		return err
 	}
 
-	err = client.SendTransaction(context.Background(), signedTx)
+	signedTx, err := m.sendApproval(ctx, chainName, from, tokenAddress, spender, amount)
 	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %v", err)
+		return err
 	}
 
-	return signedTx.Hash().Hex(), nil
-}
-
-func (m *MultiChainRouter) ApproveToken(chainName, userWalletPrivateKey string, tokenAddress, spender common.Address, amount *big.Int) error {
-	client, ok := m.Clients[chainName]
-	if !ok {
-		return fmt.Errorf("unsupported chain: %s", chainName)
+	// Mining can take longer than the call timeout, so only ctx bounds the wait
+	receipt, err := bind.WaitMined(ctx, client, signedTx)
+	if err != nil {
+		return err
 	}
-	chainConfig, exists := m.Chains[chainName]
-	if !exists {
-		return fmt.Errorf("no configuration found for chain: %s", chainName)
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return errors.New("approval transaction failed")
 	}
 
-	privateKey, err := crypto.HexToECDSA(userWalletPrivateKey)
+	return nil
+}
+
+// SendApproval approves spender to spend amount of a token without waiting for the approval
+// to be mined. Transactions sent from the same wallet afterwards take later nonces, so they
+// can't be mined before it.
+func (m *MultiChainRouter) SendApproval(ctx context.Context, chainName string, from common.Address, tokenAddress, spender common.Address, amount *big.Int) (string, error) {
+	signedTx, err := m.sendApproval(ctx, chainName, from, tokenAddress, spender, amount)
 	if err != nil {
-		return err
+		return "", err
 	}
+	return signedTx.Hash().Hex(), nil
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return errors.New("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+func (m *MultiChainRouter) sendApproval(ctx context.Context, chainName string, from common.Address, tokenAddress, spender common.Address, amount *big.Int) (*types.Transaction, error) {
+	client, chainConfig, err := m.chain(chainName)
+	if err != nil {
+		return nil, err
 	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
-	erc20ABI := `[{"constant":false,"inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"name":"approve","outputs":[{"name":"","type":"bool"}],"payable":false,"stateMutability":"nonpayable","type":"function"}]`
+	ctx, cancel := context.WithTimeout(ctx, chainConfig.Timeout())
+	defer cancel()
 
-	parsedABI, err := abi.JSON(strings.NewReader(erc20ABI))
+	signer, err := m.signer(from)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	fromAddress := signer.Address()
 
-	data, err := parsedABI.Pack("approve", spender, amount)
+	parsedABI, err := abi.JSON(strings.NewReader(erc20AllowanceABI))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	data, err := parsedABI.Pack("approve", spender, amount)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	gasPrice, err := client.SuggestGasPrice(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	msg := ethereum.CallMsg{
@@ -165,53 +199,42 @@ func (m *MultiChainRouter) ApproveToken(chainName, userWalletPrivateKey string,
 		Data: data,
 	}
 
-	gasLimit, err := estimateGas(client, msg)
-	if err != nil {
-		return err
-	}
-
-	tx := types.NewTransaction(nonce, tokenAddress, big.NewInt(0), gasLimit, gasPrice, data)
-
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainConfig.ChainID), privateKey)
+	gasLimit, err := estimateGas(ctx, client, msg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = client.SendTransaction(context.Background(), signedTx)
+	signedTx, err := m.Nonces.Send(ctx, client, signer, chainConfig, PublicBroadcaster{}, func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, tokenAddress, big.NewInt(0), gasLimit, gasPrice, data)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Printf("Approval Transaction sent: %s\n", signedTx.Hash().Hex())
 
-	receipt, err := bind.WaitMined(context.Background(), client, signedTx)
-	if err != nil {
-		return err
-	}
-
-	if receipt.Status != types.ReceiptStatusSuccessful {
-		return errors.New("approval transaction failed")
-	}
-
-	return nil
+	return signedTx, nil
 }
-func (m *MultiChainRouter) SwapTokensForETH(chainName, userWalletPrivateKey string, tokenAddress, uniswapRouterAddress, wethAddress common.Address, amountIn *big.Int) (string, error) {
-	client, ok := m.Clients[chainName]
-	if !ok {
-		return "", fmt.Errorf("unsupported chain: %s", chainName)
-	}
 
-	privateKey, err := crypto.HexToECDSA(userWalletPrivateKey)
+// SwapTokensForETH sells a token for ETH, reverting if it would return less than amountOutMin,
+// and submits the swap through the named broadcast mode. The token must already be approved.
+func (m *MultiChainRouter) SwapTokensForETH(ctx context.Context, chainName string, from common.Address, tokenAddress, uniswapRouterAddress, wethAddress common.Address, amountIn, amountOutMin *big.Int, broadcast string) (string, error) {
+	client, chainConfig, err := m.chain(chainName)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, chainConfig.Timeout())
+	defer cancel()
+	broadcaster, err := m.broadcaster(broadcast)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse private key: %v", err)
+		return "", err
 	}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return "", errors.New("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+	signer, err := m.signer(from)
+	if err != nil {
+		return "", err
 	}
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	fromAddress := signer.Address()
 
 	// Load Uniswap Router ABI
 	uniswapABI := `[{"inputs":[{"internalType":"uint256","name":"amountIn","type":"uint256"},{"internalType":"uint256","name":"amountOutMin","type":"uint256"},{"internalType":"address[]","name":"path","type":"address[]"},{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"deadline","type":"uint256"}],"name":"swapExactTokensForETH","outputs":[{"internalType":"uint256[]","name":"amounts","type":"uint256[]"}],"stateMutability":"nonpayable","type":"function"}]`
@@ -220,7 +243,6 @@ func (m *MultiChainRouter) SwapTokensForETH(chainName, userWalletPrivateKey stri
 		return "", fmt.Errorf("failed to parse Uniswap ABI: %v", err)
 	}
 
-	amountOutMin := big.NewInt(0) // Set to 0 or calculate based on slippage
 	deadline := big.NewInt(time.Now().Add(10 * time.Minute).Unix())
 
 	path := []common.Address{
@@ -233,36 +255,142 @@ func (m *MultiChainRouter) SwapTokensForETH(chainName, userWalletPrivateKey stri
 		return "", fmt.Errorf("failed to pack data: %v", err)
 	}
 
-	// Create the transaction
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	gasLimit, err := estimateGas(ctx, client, ethereum.CallMsg{
+		From: fromAddress,
+		To:   &uniswapRouterAddress,
+		Data: data,
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get nonce: %v", err)
+		return "", fmt.Errorf("failed to estimate gas: %v", err)
 	}
 
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	gasPrice, err := client.SuggestGasPrice(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get gas price: %v", err)
 	}
 
-	tx := types.NewTransaction(nonce, uniswapRouterAddress, big.NewInt(0), 300000, gasPrice, data)
+	// Sign and send the transaction with the wallet's next nonce
+	signedTx, err := m.Nonces.Send(ctx, client, signer, chainConfig, broadcaster, func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, uniswapRouterAddress, big.NewInt(0), gasLimit, gasPrice, data)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Return the transaction hash directly, no waiting for receipt
+	return signedTx.Hash().Hex(), nil
+}
+
+// Universal Router commands, and the recipients it reads as the caller and the router itself.
+const (
+	urV2SwapExactIn = 0x08
+	urPermit2Permit = 0x0a
+	urUnwrapWETH    = 0x0c
+)
 
-	// Sign the transaction
-	chainID, err := client.NetworkID(context.Background())
+var (
+	urMsgSender   = common.HexToAddress("0x0000000000000000000000000000000000000001")
+	urAddressThis = common.HexToAddress("0x0000000000000000000000000000000000000002")
+)
+
+const universalRouterABI = `[{"inputs":[{"name":"commands","type":"bytes"},{"name":"inputs","type":"bytes[]"},{"name":"deadline","type":"uint256"}],"name":"execute","outputs":[],"stateMutability":"payable","type":"function"}]`
+
+// SwapTokensForETHWithPermit2 sells a token for ETH through the Universal Router, which pulls
+// the token through Permit2. A non-nil permit is submitted ahead of the swap, otherwise the
+// router's Permit2 allowance must already cover amountIn. The swap reverts if it would return
+// less than amountOutMin, and is submitted through the named broadcast mode.
+func (m *MultiChainRouter) SwapTokensForETHWithPermit2(ctx context.Context, chainName string, from common.Address, tokenAddress, universalRouter, wethAddress common.Address, amountIn, amountOutMin *big.Int, permit *PermitSingle, broadcast string) (string, error) {
+	client, chainConfig, err := m.chain(chainName)
+	if err != nil {
+		return "", err
+	}
+	if !chainConfig.AcceptsPermit2(universalRouter) {
+		return "", fmt.Errorf("router %s is not the Universal Router of %s", universalRouter.Hex(), chainConfig.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, chainConfig.Timeout())
+	defer cancel()
+	broadcaster, err := m.broadcaster(broadcast)
 	if err != nil {
-		return "", fmt.Errorf("failed to get chain ID: %v", err)
+		return "", err
 	}
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signer, err := m.signer(from)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %v", err)
+		return "", err
 	}
+	fromAddress := signer.Address()
 
-	// Send the transaction
-	err = client.SendTransaction(context.Background(), signedTx)
+	commands, inputs, err := universalSellCommands(tokenAddress, wethAddress, amountIn, amountOutMin, permit)
 	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %v", err)
+		return "", err
 	}
 
-	// Return the transaction hash directly, no waiting for receipt
+	parsedABI, err := abi.JSON(strings.NewReader(universalRouterABI))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Universal Router ABI: %v", err)
+	}
+	deadline := big.NewInt(time.Now().Add(10 * time.Minute).Unix())
+	data, err := parsedABI.Pack("execute", commands, inputs, deadline)
+	if err != nil {
+		return "", fmt.Errorf("failed to pack data: %v", err)
+	}
+
+	gasLimit, err := estimateGas(ctx, client, ethereum.CallMsg{
+		From: fromAddress,
+		To:   &universalRouter,
+		Data: data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to estimate gas: %v", err)
+	}
+
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gas price: %v", err)
+	}
+
+	signedTx, err := m.Nonces.Send(ctx, client, signer, chainConfig, broadcaster, func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, universalRouter, big.NewInt(0), gasLimit, gasPrice, data)
+	})
+	if err != nil {
+		return "", err
+	}
 	return signedTx.Hash().Hex(), nil
 }
+
+// universalSellCommands returns the Universal Router commands and inputs that sell amountIn of
+// a token from the caller into WETH held by the router, then unwrap it to the caller as ETH,
+// after applying permit when there is one.
+func universalSellCommands(token, weth common.Address, amountIn, amountOutMin *big.Int, permit *PermitSingle) ([]byte, [][]byte, error) {
+	var commands []byte
+	var inputs [][]byte
+
+	if permit != nil {
+		input, err := permit.abiEncode()
+		if err != nil {
+			return nil, nil, err
+		}
+		commands = append(commands, urPermit2Permit)
+		inputs = append(inputs, input)
+	}
+
+	address, _ := abi.NewType("address", "", nil)
+	uint256, _ := abi.NewType("uint256", "", nil)
+	addresses, _ := abi.NewType("address[]", "", nil)
+	boolean, _ := abi.NewType("bool", "", nil)
+
+	// The caller pays, through Permit2, and the router keeps the WETH to unwrap it
+	swap, err := abi.Arguments{{Type: address}, {Type: uint256}, {Type: uint256}, {Type: addresses}, {Type: boolean}}.
+		Pack(urAddressThis, amountIn, amountOutMin, []common.Address{token, weth}, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pack swap: %v", err)
+	}
+	unwrap, err := abi.Arguments{{Type: address}, {Type: uint256}}.Pack(urMsgSender, amountOutMin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pack unwrap: %v", err)
+	}
+
+	commands = append(commands, urV2SwapExactIn, urUnwrapWETH)
+	inputs = append(inputs, swap, unwrap)
+	return commands, inputs, nil
+}
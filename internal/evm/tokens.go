@@ -0,0 +1,273 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"copytrader/internal/cache"
+	database "copytrader/internal/db"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+)
+
+// ABI for ERC20 standard functions `decimals` and `totalSupply`
+const erc20MetadataABI = `[{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"totalSupply","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// DefaultTokenTTL is how long token metadata stays in Redis before it is resolved again.
+const DefaultTokenTTL = 24 * time.Hour
+
+// TokenMetadata describes an ERC20 token.
+type TokenMetadata struct {
+	Address     common.Address `json:"address"`
+	Name        string         `json:"name"`
+	Symbol      string         `json:"symbol"`
+	Decimals    uint8          `json:"decimals"`
+	TotalSupply *big.Int       `json:"totalSupply"`
+}
+
+// FetchTokenDecimals fetches the number of decimals of a token.
+func FetchTokenDecimals(ctx context.Context, client *ethclient.Client, contractAddress common.Address) (uint8, error) {
+	tokenABI, err := abi.JSON(strings.NewReader(erc20MetadataABI))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ABI: %v", err)
+	}
+
+	callMsg := ethereum.CallMsg{To: &contractAddress, Data: tokenABI.Methods["decimals"].ID}
+	result, err := client.CallContract(ctx, callMsg, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch token decimals: %v", err)
+	}
+
+	var decimals uint8
+	err = tokenABI.UnpackIntoInterface(&decimals, "decimals", result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unpack token decimals: %v", err)
+	}
+
+	return decimals, nil
+}
+
+// FetchTokenTotalSupply fetches the total supply of a token in base units.
+func FetchTokenTotalSupply(ctx context.Context, client *ethclient.Client, contractAddress common.Address) (*big.Int, error) {
+	tokenABI, err := abi.JSON(strings.NewReader(erc20MetadataABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %v", err)
+	}
+
+	callMsg := ethereum.CallMsg{To: &contractAddress, Data: tokenABI.Methods["totalSupply"].ID}
+	result, err := client.CallContract(ctx, callMsg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token total supply: %v", err)
+	}
+
+	var totalSupply *big.Int
+	err = tokenABI.UnpackIntoInterface(&totalSupply, "totalSupply", result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack token total supply: %v", err)
+	}
+
+	return totalSupply, nil
+}
+
+// FetchTokenMetadata resolves name, symbol, decimals and total supply of a token from the chain.
+func FetchTokenMetadata(ctx context.Context, client *ethclient.Client, contractAddress common.Address) (*TokenMetadata, error) {
+	name, symbol, err := FetchTokenDetails(ctx, client, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	decimals, err := FetchTokenDecimals(ctx, client, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	totalSupply, err := FetchTokenTotalSupply(ctx, client, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenMetadata{
+		Address:     contractAddress,
+		Name:        name,
+		Symbol:      symbol,
+		Decimals:    decimals,
+		TotalSupply: totalSupply,
+	}, nil
+}
+
+// FormatUnits renders an amount of base units as a decimal string with the given number of decimals.
+func FormatUnits(amount *big.Int, decimals uint8) string {
+	if amount == nil {
+		return "0"
+	}
+	if decimals == 0 {
+		return amount.String()
+	}
+
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+
+	whole := digits[:len(digits)-int(decimals)]
+	fraction := strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+
+	formatted := whole
+	if fraction != "" {
+		formatted += "." + fraction
+	}
+	if amount.Sign() < 0 {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+// ParseUnits converts a decimal string such as "0.05" into base units with the given number of decimals.
+func ParseUnits(value string, decimals uint8) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount: %q", value)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", value, decimals)
+	}
+	return amount.Num(), nil
+}
+
+// TokenRegistry resolves token metadata once per token, keeping it in memory, in Redis
+// and in the Token table so that later lookups never hit the chain.
+type TokenRegistry struct {
+	Chain  string
+	Client *ethclient.Client
+	Cache  *cache.Cache
+	DB     *database.Database
+	TTL    time.Duration
+
+	mu     sync.RWMutex
+	tokens map[common.Address]*TokenMetadata
+}
+
+func NewTokenRegistry(chain string, client *ethclient.Client, c *cache.Cache, db *database.Database, ttl time.Duration) *TokenRegistry {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return &TokenRegistry{
+		Chain:  chain,
+		Client: client,
+		Cache:  c,
+		DB:     db,
+		TTL:    ttl,
+		tokens: make(map[common.Address]*TokenMetadata),
+	}
+}
+
+func (r *TokenRegistry) cacheKey(tokenAddress common.Address) string {
+	return fmt.Sprintf("token:%s:%s", r.Chain, strings.ToLower(tokenAddress.Hex()))
+}
+
+// Get returns the metadata for a token, checking memory, Redis and the database before the chain.
+func (r *TokenRegistry) Get(ctx context.Context, tokenAddress common.Address) (*TokenMetadata, error) {
+	r.mu.RLock()
+	token, ok := r.tokens[tokenAddress]
+	r.mu.RUnlock()
+	if ok {
+		return token, nil
+	}
+
+	token = &TokenMetadata{}
+	if r.Cache != nil {
+		err := r.Cache.GetJSON(ctx, r.cacheKey(tokenAddress), token)
+		if err == nil {
+			r.remember(token)
+			return token, nil
+		}
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			log.Printf("Error reading token %s from cache: %v", tokenAddress.Hex(), err)
+		}
+	}
+
+	token, err := r.load(ctx, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Cache != nil {
+		if err := r.Cache.SetJSON(ctx, r.cacheKey(tokenAddress), token, r.TTL); err != nil {
+			log.Printf("Error caching token %s: %v", tokenAddress.Hex(), err)
+		}
+	}
+	r.remember(token)
+
+	return token, nil
+}
+
+// load reads the token from the database, falling back to the chain and persisting the result.
+func (r *TokenRegistry) load(ctx context.Context, tokenAddress common.Address) (*TokenMetadata, error) {
+	if r.DB != nil {
+		row, err := r.DB.GetTokenByCA(ctx, r.Chain, tokenAddress.Hex())
+		if err == nil {
+			totalSupply, ok := new(big.Int).SetString(row.TotalSupply, 10)
+			if !ok {
+				totalSupply = new(big.Int)
+			}
+			return &TokenMetadata{
+				Address:     tokenAddress,
+				Name:        row.Name,
+				Symbol:      row.Symbol,
+				Decimals:    row.Decimals,
+				TotalSupply: totalSupply,
+			}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Error reading token %s from database: %v", tokenAddress.Hex(), err)
+		}
+	}
+
+	token, err := FetchTokenMetadata(ctx, r.Client, tokenAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.DB != nil {
+		err := r.DB.SaveToken(ctx, database.Token{
+			Chain:           r.Chain,
+			ContractAddress: tokenAddress.Hex(),
+			Name:            token.Name,
+			Symbol:          token.Symbol,
+			Decimals:        token.Decimals,
+			TotalSupply:     token.TotalSupply.String(),
+		})
+		if err != nil {
+			log.Printf("Error saving token %s: %v", tokenAddress.Hex(), err)
+		}
+	}
+
+	return token, nil
+}
+
+func (r *TokenRegistry) remember(token *TokenMetadata) {
+	r.mu.Lock()
+	r.tokens[token.Address] = token
+	r.mu.Unlock()
+}
+
+// FormatAmount renders a raw token amount using the token's decimals and symbol.
+func (r *TokenRegistry) FormatAmount(ctx context.Context, tokenAddress common.Address, amount *big.Int) string {
+	token, err := r.Get(ctx, tokenAddress)
+	if err != nil {
+		log.Printf("Error resolving token %s: %v", tokenAddress.Hex(), err)
+		return amount.String()
+	}
+	return FormatUnits(amount, token.Decimals) + " " + token.Symbol
+}
@@ -0,0 +1,42 @@
+package evm
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TransferGasLimit is the gas used by a plain ETH transfer to an externally owned account.
+const TransferGasLimit = 21000
+
+// TransferETH sends amount wei of ETH from one managed wallet to any address.
+func (m *MultiChainRouter) TransferETH(ctx context.Context, chainName string, from, to common.Address, amount *big.Int) (string, error) {
+	client, chainConfig, err := m.chain(chainName)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, chainConfig.Timeout())
+	defer cancel()
+
+	signer, err := m.signer(from)
+	if err != nil {
+		return "", err
+	}
+
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gas price: %v", err)
+	}
+
+	signedTx, err := m.Nonces.Send(ctx, client, signer, chainConfig, PublicBroadcaster{}, func(nonce uint64) *types.Transaction {
+		return types.NewTransaction(nonce, to, amount, TransferGasLimit, gasPrice, nil)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return signedTx.Hash().Hex(), nil
+}
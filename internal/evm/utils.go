@@ -2,7 +2,6 @@ package evm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum"
@@ -13,12 +12,11 @@ import (
 	"log"
 	"math"
 	"math/big"
-	"net/http"
 	"strings"
 )
 
-func GetETHBalance(client *ethclient.Client, address common.Address) (*big.Float, error) {
-	balance, err := client.BalanceAt(context.Background(), address, nil)
+func GetETHBalance(ctx context.Context, client *ethclient.Client, address common.Address) (*big.Float, error) {
+	balance, err := GetETHBalanceWei(ctx, client, address)
 	if err != nil {
 		return nil, err
 	}
@@ -28,72 +26,25 @@ func GetETHBalance(client *ethclient.Client, address common.Address) (*big.Float
 	return balanceInETH, nil
 }
 
-func GetEthereumPrice() (int, error) {
-	// Define the struct to hold the API response within the function
-	type PriceResponse struct {
-		Ethereum struct {
-			Usd float64 `json:"usd"`
-		} `json:"ethereum"`
-	}
-
-	url := "https://api.coingecko.com/api/v3/simple/price?ids=ethereum&vs_currencies=usd"
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, errors.New("failed to fetch data from API")
-	}
-
-	var priceResp PriceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&priceResp); err != nil {
-		return 0, err
-	}
-
-	// Convert the price to an integer (rounding down)
-	priceInInt := int(priceResp.Ethereum.Usd)
-
-	return priceInInt, nil
+// GetETHBalanceWei returns the ETH balance of an address in wei.
+func GetETHBalanceWei(ctx context.Context, client *ethclient.Client, address common.Address) (*big.Int, error) {
+	return client.BalanceAt(ctx, address, nil)
 }
 
-// CalculateTokenPriceInUSD calculates the price of the token in USD based on the amount of ETH and tokens received.
-func CalculateTokenPriceInUSD(amountEth *big.Int, amountTokens *big.Int) (float64, error) {
-	// Fetch the current price of Ethereum in USD
-	ethPriceInUSD, err := GetEthereumPrice()
+// GetEthereumPrice returns the current ETH/USD price from the given source.
+func GetEthereumPrice(ctx context.Context, source PriceSource) (float64, error) {
+	price, err := source.ETHPriceUSD(ctx)
 	if err != nil {
-		log.Printf("Error getting Ethereum price: %v", err)
 		return 0, err
 	}
-
-	// Convert the price from int to float64 for precise division
-	ethPriceInUSDf := float64(ethPriceInUSD)
-
-	// Convert the amount of ETH from wei to ETH (1 ETH = 10^18 wei)
-	ethInFloat64 := new(big.Float).Quo(new(big.Float).SetInt(amountEth), big.NewFloat(1e18))
-
-	// Convert amountTokens to float64 for division
-	tokensInFloat64 := new(big.Float).SetInt(amountTokens)
-
-	// Calculate the USD value of the ETH used in the swap
-	ethValueInUSD := new(big.Float).Mul(ethInFloat64, big.NewFloat(ethPriceInUSDf))
-
-	// Calculate the price per token in USD
-	tokenPriceInUSD := new(big.Float).Quo(ethValueInUSD, tokensInFloat64)
-
-	// Convert to float64
-	tokenPriceInUSDf, _ := tokenPriceInUSD.Float64()
-
-	return tokenPriceInUSDf, nil
+	return price.Float64(), nil
 }
 
 // ERC20 ABI for the `balanceOf` function
 const erc20BalanceABI = `[{"constant":true,"inputs":[{"name":"_owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"balance","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"}]`
 
 // GetTokenBalance retrieves the token balance of a given address for a specific ERC20 token contract.
-func GetTokenBalance(client *ethclient.Client, tokenAddr, ownerAddr common.Address) (*big.Int, error) {
+func GetTokenBalance(ctx context.Context, client *ethclient.Client, tokenAddr, ownerAddr common.Address) (*big.Int, error) {
 	// Parse the ABI
 	tokenABI, err := abi.JSON(strings.NewReader(erc20BalanceABI))
 	if err != nil {
@@ -112,7 +63,6 @@ func GetTokenBalance(client *ethclient.Client, tokenAddr, ownerAddr common.Addre
 	}
 
 	// Make the call to the token contract
-	ctx := context.Background()
 	result, err := client.CallContract(ctx, callMsg, nil)
 	if err != nil {
 		return nil, errors.New("failed to call contract")
@@ -132,16 +82,13 @@ func GetTokenBalance(client *ethclient.Client, tokenAddr, ownerAddr common.Addre
 const erc20ABI = `[{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"payable":false,"stateMutability":"view","type":"function"},{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"payable":false,"stateMutability":"view","type":"function"}]`
 
 // FetchTokenDetails fetches the token name and symbol for a given contract address
-func FetchTokenDetails(client *ethclient.Client, contractAddress common.Address) (string, string, error) {
+func FetchTokenDetails(ctx context.Context, client *ethclient.Client, contractAddress common.Address) (string, string, error) {
 	// Parse the ABI
 	tokenABI, err := abi.JSON(strings.NewReader(erc20ABI))
 	if err != nil {
 		return "", "", fmt.Errorf("failed to parse ABI: %v", err)
 	}
 
-	// Create a new call context
-	ctx := context.Background()
-
 	// Fetch the token name
 	callMsg := ethereum.CallMsg{To: &contractAddress, Data: tokenABI.Methods["name"].ID}
 	result, err := client.CallContract(ctx, callMsg, nil)
@@ -171,7 +118,7 @@ func FetchTokenDetails(client *ethclient.Client, contractAddress common.Address)
 	return tokenName, tokenSymbol, nil
 }
 
-func GetEstimatedTokensForETH(client *ethclient.Client, routerAddress, tokenAddress, WETH_ADDRESS_ common.Address, amountEth *big.Int) (*big.Int, error) {
+func GetEstimatedTokensForETH(ctx context.Context, client *ethclient.Client, routerAddress, tokenAddress, WETH_ADDRESS_ common.Address, amountEth *big.Int) (*big.Int, error) {
 	router, err := NewRouter(routerAddress, client)
 	if err != nil {
 		log.Printf("Error creating Uniswap router: %v", err)
@@ -183,7 +130,7 @@ func GetEstimatedTokensForETH(client *ethclient.Client, routerAddress, tokenAddr
 	callOpts := &bind.CallOpts{
 		Pending: false,
 		From:    WETH_ADDRESS_,
-		Context: context.Background(),
+		Context: ctx,
 	}
 
 	amounts, err := router.GetAmountsOut(callOpts, amountEth, []common.Address{WETH_ADDRESS_, tokenAddress})
@@ -198,17 +145,39 @@ func GetEstimatedTokensForETH(client *ethclient.Client, routerAddress, tokenAddr
 	return amounts[1], nil
 }
 
-func estimateGas(client *ethclient.Client, msg ethereum.CallMsg) (uint64, error) {
-	gasLimit, err := client.EstimateGas(context.Background(), msg)
+// GetEstimatedETHForTokens quotes the WETH received for selling amountTokens of a token.
+func GetEstimatedETHForTokens(ctx context.Context, client *ethclient.Client, routerAddress, tokenAddress, WETH_ADDRESS_ common.Address, amountTokens *big.Int) (*big.Int, error) {
+	router, err := NewRouter(routerAddress, client)
+	if err != nil {
+		log.Printf("Error creating Uniswap router: %v", err)
+		return nil, err
+	}
+
+	callOpts := &bind.CallOpts{
+		Pending: false,
+		Context: ctx,
+	}
+
+	amounts, err := router.GetAmountsOut(callOpts, amountTokens, []common.Address{tokenAddress, WETH_ADDRESS_})
+	if err != nil {
+		log.Printf("Error getting estimated ETH: %v", err)
+		return nil, err
+	}
+
+	return amounts[1], nil
+}
+
+func estimateGas(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg) (uint64, error) {
+	gasLimit, err := client.EstimateGas(ctx, msg)
 	if err != nil {
 		return 0, err
 	}
 	return gasLimit, nil
 }
 
-func CalculateMinTokens(client *ethclient.Client, routerAddress common.Address, tokenAddress, WETH_ADDRESS_ common.Address, amountEth *big.Int, slippage float64) (*big.Int, error) {
+func CalculateMinTokens(ctx context.Context, client *ethclient.Client, routerAddress common.Address, tokenAddress, WETH_ADDRESS_ common.Address, amountEth *big.Int, slippage float64) (*big.Int, error) {
 	// Estimate the number of tokens you would get for the specified ETH amount
-	estimatedTokens, err := GetEstimatedTokensForETH(client, routerAddress, tokenAddress, WETH_ADDRESS_, amountEth)
+	estimatedTokens, err := GetEstimatedTokensForETH(ctx, client, routerAddress, tokenAddress, WETH_ADDRESS_, amountEth)
 	if err != nil {
 		log.Printf("Error getting estimated tokens: %v", err)
 		return nil, err
@@ -225,3 +194,23 @@ func CalculateMinTokens(client *ethclient.Client, routerAddress common.Address,
 
 	return minTokens, nil
 }
+
+// callView packs and executes a read-only contract call at the given block (nil for latest) and unpacks the outputs.
+func callView(ctx context.Context, client *ethclient.Client, parsedABI abi.ABI, contract common.Address, blockNumber *big.Int, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := parsedABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %v", method, err)
+	}
+
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %v", method, err)
+	}
+
+	values, err := parsedABI.Unpack(method, result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %v", method, err)
+	}
+
+	return values, nil
+}
@@ -0,0 +1,208 @@
+package treasury
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+
+	database "copytrader/internal/db"
+	"copytrader/internal/evm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Treasury moves ETH between the master wallet and the managed follower wallets,
+// recording every transfer in the database.
+type Treasury struct {
+	Chain   string
+	Router  *evm.MultiChainRouter
+	DB      *database.Database
+	Master  common.Address
+	Wallets []common.Address
+	// Tokens are the tokens included in balance reports.
+	Tokens []common.Address
+}
+
+// WalletBalance is a snapshot of one wallet's holdings.
+type WalletBalance struct {
+	Address common.Address
+	ETH     *big.Float
+	Wei     *big.Int
+	Tokens  map[common.Address]*big.Int
+}
+
+func (t *Treasury) client() (*ethclient.Client, error) {
+	return t.Router.Client(t.Chain)
+}
+
+// Balances reads the ETH and tracked token balances of the master and every managed wallet.
+func (t *Treasury) Balances(ctx context.Context) ([]WalletBalance, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := append([]common.Address{t.Master}, t.Wallets...)
+	balances := make([]WalletBalance, 0, len(addresses))
+	for _, address := range addresses {
+		wei, err := evm.GetETHBalanceWei(ctx, client, address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ETH balance of %s: %v", address.Hex(), err)
+		}
+
+		balance := WalletBalance{
+			Address: address,
+			ETH:     new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)),
+			Wei:     wei,
+			Tokens:  make(map[common.Address]*big.Int),
+		}
+		for _, token := range t.Tokens {
+			amount, err := evm.GetTokenBalance(ctx, client, token, address)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s balance of %s: %v", token.Hex(), address.Hex(), err)
+			}
+			balance.Tokens[token] = amount
+		}
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+// TopUp sends ETH from the master wallet to every managed wallet holding less than threshold,
+// bringing it up to target.
+func (t *Treasury) TopUp(ctx context.Context, threshold, target *big.Int) ([]database.Transfer, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []database.Transfer
+	for _, wallet := range t.Wallets {
+		balance, err := evm.GetETHBalanceWei(ctx, client, wallet)
+		if err != nil {
+			return transfers, fmt.Errorf("failed to get ETH balance of %s: %v", wallet.Hex(), err)
+		}
+		if balance.Cmp(threshold) >= 0 {
+			continue
+		}
+
+		amount := new(big.Int).Sub(target, balance)
+		if amount.Sign() <= 0 {
+			continue
+		}
+
+		transfer, err := t.transfer(ctx, database.TransferKindTopUp, t.Master, wallet, amount)
+		if err != nil {
+			return transfers, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
+
+// Sweep sends everything above keep from each managed wallet back to the master wallet.
+// keep must cover the gas of future trades and of the sweep itself.
+func (t *Treasury) Sweep(ctx context.Context, keep *big.Int) ([]database.Transfer, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []database.Transfer
+	for _, wallet := range t.Wallets {
+		balance, err := evm.GetETHBalanceWei(ctx, client, wallet)
+		if err != nil {
+			return transfers, fmt.Errorf("failed to get ETH balance of %s: %v", wallet.Hex(), err)
+		}
+
+		amount := new(big.Int).Sub(balance, keep)
+		if amount.Sign() <= 0 {
+			continue
+		}
+
+		transfer, err := t.transfer(ctx, database.TransferKindSweep, wallet, t.Master, amount)
+		if err != nil {
+			return transfers, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
+
+// Rebalance moves each managed wallet to its target ETH balance: wallets above target send the
+// excess to the master wallet first, then wallets below target are funded from it.
+// Wallets without a target are left alone.
+func (t *Treasury) Rebalance(ctx context.Context, targets map[common.Address]*big.Int) ([]database.Transfer, error) {
+	client, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	deficits := make(map[common.Address]*big.Int)
+	var transfers []database.Transfer
+	for _, wallet := range t.Wallets {
+		target, ok := targets[wallet]
+		if !ok {
+			continue
+		}
+
+		balance, err := evm.GetETHBalanceWei(ctx, client, wallet)
+		if err != nil {
+			return transfers, fmt.Errorf("failed to get ETH balance of %s: %v", wallet.Hex(), err)
+		}
+
+		diff := new(big.Int).Sub(balance, target)
+		switch diff.Sign() {
+		case 1:
+			transfer, err := t.transfer(ctx, database.TransferKindRebalance, wallet, t.Master, diff)
+			if err != nil {
+				return transfers, err
+			}
+			transfers = append(transfers, transfer)
+		case -1:
+			deficits[wallet] = diff.Neg(diff)
+		}
+	}
+
+	for _, wallet := range t.Wallets {
+		amount, ok := deficits[wallet]
+		if !ok {
+			continue
+		}
+		transfer, err := t.transfer(ctx, database.TransferKindRebalance, t.Master, wallet, amount)
+		if err != nil {
+			return transfers, err
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers, nil
+}
+
+func (t *Treasury) transfer(ctx context.Context, kind string, from, to common.Address, amount *big.Int) (database.Transfer, error) {
+	hash, err := t.Router.TransferETH(ctx, t.Chain, from, to, amount)
+	if err != nil {
+		return database.Transfer{}, fmt.Errorf("failed to transfer %s wei from %s to %s: %v", amount, from.Hex(), to.Hex(), err)
+	}
+
+	log.Printf("Treasury %s: sent %s ETH from %s to %s (%s)", kind, evm.FormatUnits(amount, 18), from.Hex(), to.Hex(), hash)
+
+	transfer := database.Transfer{
+		Chain:       t.Chain,
+		Kind:        kind,
+		FromAddress: from.Hex(),
+		ToAddress:   to.Hex(),
+		AmountWei:   amount.String(),
+		Hash:        hash,
+	}
+	if err := t.DB.CreateTransfer(ctx, transfer); err != nil {
+		return transfer, fmt.Errorf("failed to record transfer %s: %v", hash, err)
+	}
+
+	return transfer, nil
+}